@@ -1,63 +1,220 @@
 package poseidon2
 
 import (
+	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"sort"
 )
 
 // Hash computes Poseidon2 hash of multiple field elements
 // Uses sponge construction with domain separation
 func Hash(elements ...Fr) Fr {
-	if len(elements) == 0 {
-		// Return hash of empty input (zero)
-		hasher := NewHasher()
-		return hasher.Finalize()
-	}
-	
 	hasher := NewHasher()
 	hasher.AbsorbMany(elements)
 	return hasher.Finalize()
 }
 
-// Compress2 performs two-to-one hash compression for Merkle trees
-// Optimized for tree construction: Hash(a, b)
-func Compress2(a, b Fr) Fr {
+// HashWithIV computes Hash(elements...) with the capacity element seeded by iv
+// Use it to reproduce sponges that start from a specific initial capacity value;
+// a zero IV gives exactly Hash(elements...)
+func HashWithIV(iv Fr, elements ...Fr) Fr {
+	hasher := NewHasherWithIV(iv)
+	hasher.AbsorbMany(elements)
+	return hasher.Finalize()
+}
+
+// WideHash computes a two-element (~508-bit) digest of elements
+// The elements are absorbed as in Hash and both rate elements of the final state
+// are squeezed, so the first output equals Hash(elements...)
+func WideHash(elements ...Fr) (Fr, Fr) {
 	hasher := NewHasher()
+	hasher.AbsorbMany(elements)
+	out := hasher.SqueezeN(2)
+	return out[0], out[1]
+}
+
+// HashWithLengthTag hashes elements with the input length encoded in the capacity
+// The capacity element is seeded with 2^64 + len(elements) before absorbing, the
+// Neptune/Filecoin convention for variable-length hashing, so inputs of different
+// lengths start from different states and cannot collide through zero padding
+func HashWithLengthTag(elements ...Fr) Fr {
+	hasher := NewHasherWithIV(lengthTag(len(elements)))
+	hasher.AbsorbMany(elements)
+	return hasher.Finalize()
+}
+
+// domainCounted marks the capacity of HashCounted, keeping it apart from plain Hash
+const domainCounted Domain = 0x53474354 // "SGCT"
+
+// HashCounted hashes elements with their count absorbed first
+// The capacity is seeded with a reserved constant and FromUint64(len(elements)) is
+// absorbed ahead of the elements, so inputs of different lengths never collide
+// whatever the padding, e.g. HashCounted(a) != HashCounted(a, Zero()), and no Hash
+// input reproduces the digest. It is the recommended general-purpose hash for
+// variable-length input; Hash is kept for compatibility with existing digests and
+// HashWithLengthTag for the Neptune convention
+func HashCounted(elements ...Fr) Fr {
+	hasher := NewHasherWithIV(FromUint64(uint64(domainCounted)))
+	hasher.Absorb(FromUint64(uint64(len(elements))))
+	hasher.AbsorbMany(elements)
+	return hasher.Finalize()
+}
+
+// lengthTag returns the field element 2^64 + n
+func lengthTag(n int) Fr {
+	tag := Fr{uint64(n), 1, 0, 0} // Regular form of 2^64 + n
+	var result Fr
+	result.Mul(&tag, &montgomeryR2)
+	return result
+}
+
+// Hash1 computes Hash(a) without the variadic slice allocation
+func Hash1(a Fr) Fr {
+	var hasher Hasher
+	hasher.Absorb(a)
+	return hasher.Finalize()
+}
+
+// Hash2 computes Hash(a, b) without the variadic slice allocation
+func Hash2(a, b Fr) Fr {
+	var hasher Hasher
+	hasher.Absorb(a)
+	hasher.Absorb(b)
+	return hasher.Finalize()
+}
+
+// Hash3 computes Hash(a, b, c) without the variadic slice allocation
+func Hash3(a, b, c Fr) Fr {
+	var hasher Hasher
 	hasher.Absorb(a)
 	hasher.Absorb(b)
+	hasher.Absorb(c)
+	return hasher.Finalize()
+}
+
+// HashUint64 computes Hash over FromUint64 of each value without building a []Fr
+func HashUint64(values ...uint64) Fr {
+	hasher := NewHasher()
+	for _, v := range values {
+		hasher.Absorb(FromUint64(v))
+	}
+	return hasher.Finalize()
+}
+
+// domainSet marks the capacity of HashSet, keeping sorted-set digests apart from sequence hashes
+const domainSet Domain = 0x53475354 // "SGST"
+
+// HashSet hashes an unordered collection of elements under tag
+// The elements are sorted by canonical value (Cmp) before hashing, so any
+// permutation of the same elements gives the same digest. The capacity is seeded
+// with a reserved set constant, then tag, the count and the sorted elements are
+// absorbed, so a set digest never equals an ordered-sequence hash of the same
+// values. Duplicates are NOT removed: {a, a} and {a} hash differently, so
+// deduplicate first if a true set (rather than a multiset) is meant
+func HashSet(tag Domain, elements []Fr) Fr {
+	sorted := append([]Fr(nil), elements...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Cmp(&sorted[j]) < 0
+	})
+	
+	hasher := NewHasherWithIV(FromUint64(uint64(domainSet)))
+	hasher.Absorb(FromUint64(uint64(tag)))
+	hasher.Absorb(FromUint64(uint64(len(sorted))))
+	hasher.AbsorbMany(sorted)
+	return hasher.Finalize()
+}
+
+// domainVarUints marks the capacity of HashVarUints, keeping it apart from HashMany
+const domainVarUints Domain = 0x53475655 // "SGVU"
+
+// HashVarUints hashes a sequence of integers with its length under tag
+// The capacity is seeded with a reserved constant, then tag, len(values) and each
+// value are absorbed, so [1, 2] and [1, 2, 0] (and the empty sequence and [0]) hash
+// differently, unlike HashUint64, and no HashMany input reproduces the digest
+func HashVarUints(tag Domain, values []uint64) Fr {
+	hasher := NewHasherWithIV(FromUint64(uint64(domainVarUints)))
+	hasher.Absorb(FromUint64(uint64(tag)))
+	hasher.Absorb(FromUint64(uint64(len(values))))
+	for _, v := range values {
+		hasher.Absorb(FromUint64(v))
+	}
+	return hasher.Finalize()
+}
+
+// HashAddress hashes a 20-byte address (such as an Ethereum address) under tag
+// The address is zero-extended on the left into 32 bytes, i.e. right-aligned in
+// bytes 12..31 as the EVM stores it in a word, so the absorbed element equals the
+// address read as a big-endian uint160. Absorbs tag, then that element
+func HashAddress(tag Domain, addr [20]byte) Fr {
+	var word [32]byte
+	copy(word[12:], addr[:])
+	
+	hasher := NewHasher()
+	hasher.Absorb(FromUint64(uint64(tag)))
+	hasher.Absorb(FromBytes(word))
 	return hasher.Finalize()
 }
 
+// Compress2 is a generic two-to-one hash with a zero capacity element
+// Runs exactly one permutation over [a, b, 0] and returns the first element,
+// which equals Hash(a, b) without going through the sponge bookkeeping. It is
+// not the Merkle node compression: trees from BuildMerkleTree use MerkleNode,
+// so parents computed with Compress2 never match their roots
+func Compress2(a, b Fr) Fr {
+	state := [T]Fr{a, b, Zero()}
+	ProductionPermutation(&state)
+	return state[0]
+}
+
+// HashTwoFast hashes two elements with one permutation and no sponge bookkeeping
+// It is Compress2, which gives the same output as Hash(a, b) and Hash2(a, b): with
+// rate 2 the sponge absorbs both elements into a zero state and permutes exactly once
+func HashTwoFast(a, b Fr) Fr {
+	return Compress2(a, b)
+}
+
+// Compress2Bytes returns Compress2(a, b) in canonical 32-byte big-endian form
+// It is the byte-oriented counterpart of Compress2 for callers that store digests
+// as [32]byte; see HashPair when the inputs are bytes as well. Like Compress2 it
+// is not tree-compatible: use MerkleNodeBytes for Merkle nodes
+func Compress2Bytes(a, b Fr) [32]byte {
+	result := Compress2(a, b)
+	return result.ToBytes32()
+}
+
+// HashToBytes returns Hash(elements...) in canonical 32-byte big-endian form
+// With no elements it returns the bytes of the fixed empty hash, Hash() = Perm(0, 0, 0)[0]
+func HashToBytes(elements ...Fr) [32]byte {
+	result := Hash(elements...)
+	return result.ToBytes32()
+}
+
 // HashBytes hashes arbitrary byte data with domain separation
-// Domain tag is absorbed first, then data is parsed as field elements
+// Domain tag is absorbed first, then each non-empty chunk as PackBytes(chunk)
 func HashBytes(tag Domain, data ...[]byte) ([32]byte, error) {
+	return HashBytesWithEndian(tag, false, data...)
+}
+
+// HashBytesWithEndian is HashBytes with a selectable byte-to-field convention
+// With littleEndian false it is exactly HashBytes. With littleEndian true each
+// 31-byte group is read little-endian (first byte least significant), so a short
+// final group occupies the low-order bytes as FromBytesLE would place it. The
+// group layout and trailing length element are the same in both modes
+func HashBytesWithEndian(tag Domain, littleEndian bool, data ...[]byte) ([32]byte, error) {
 	hasher := NewHasher()
 	
 	// Absorb domain tag first
 	domainFr := FromUint64(uint64(tag))
 	hasher.Absorb(domainFr)
 	
-	// Process each data chunk
+	// Process each data chunk; empty chunks contribute nothing
 	for _, chunk := range data {
 		if len(chunk) == 0 {
 			continue
 		}
-		
-		// Convert bytes to field elements
-		// Process in 31-byte chunks to stay under field modulus
-		for i := 0; i < len(chunk); i += 31 {
-			end := i + 31
-			if end > len(chunk) {
-				end = len(chunk)
-			}
-			
-			// Pad to 32 bytes and convert to field element
-			var padded [32]byte
-			copy(padded[32-(end-i):], chunk[i:end]) // Right-align in 32-byte array
-			
-			element := FromBytes(padded)
-			hasher.Absorb(element)
-		}
+		hasher.AbsorbMany(packBytesWithEndian(chunk, littleEndian))
 	}
 	
 	// Get hash result and convert to bytes
@@ -65,6 +222,105 @@ func HashBytes(tag Domain, data ...[]byte) ([32]byte, error) {
 	return result.ToBytes32(), nil
 }
 
+// PackBytes decomposes data into field elements
+// data is split into 31-byte groups from the start; each group is read as a
+// big-endian integer (the final, possibly shorter group likewise, so it is
+// right-aligned) and a last element holding len(data) is appended. Every element
+// is below 2^248 < r, and the trailing length fixes the group count and the width
+// of the final group, so the encoding is injective across lengths and contents
+func PackBytes(data []byte) []Fr {
+	return packBytesWithEndian(data, false)
+}
+
+// packBytesWithEndian is PackBytes with each group read little-endian when littleEndian is set
+// It is the single implementation of the encoding behind PackBytes and HashBytesWithEndian
+func packBytesWithEndian(data []byte, littleEndian bool) []Fr {
+	groups := (len(data) + 30) / 31
+	out := make([]Fr, groups+1)
+	for i := 0; i < groups; i++ {
+		out[i] = packGroup(data, i, littleEndian)
+	}
+	out[groups] = FromUint64(uint64(len(data)))
+	return out
+}
+
+// packGroup returns element i of packBytesWithEndian(data, littleEndian) for i below the group count
+// A big-endian group is right-aligned in 32 bytes; a little-endian one occupies the low-order bytes
+func packGroup(data []byte, i int, littleEndian bool) Fr {
+	start := i * 31
+	end := start + 31
+	if end > len(data) {
+		end = len(data)
+	}
+	
+	var padded [32]byte
+	if littleEndian {
+		copy(padded[:], data[start:end])
+		return FromBytesLE(padded)
+	}
+	copy(padded[32-(end-start):], data[start:end])
+	return FromBytes(padded)
+}
+
+// HashBytesCT hashes data with domain separation in time that depends only on len(data)
+// The same PackBytes encoding as HashBytes is used, so the digest is identical, but no
+// validation or content heuristics run: every group goes through the same branch-free
+// FromBytes/Absorb path. The length of data is NOT hidden, only its contents
+func HashBytesCT(tag Domain, data []byte) [32]byte {
+	hasher := NewHasher()
+	hasher.Absorb(FromUint64(uint64(tag)))
+	if len(data) > 0 { // Matches HashBytes skipping empty chunks
+		absorbBytesCT(hasher, data)
+	}
+	
+	result := hasher.Finalize()
+	return result.ToBytes32()
+}
+
+// absorbBytesCT absorbs PackBytes(data) without allocating and returns the group count
+// Loop bounds and slice offsets are derived from len(data) alone
+func absorbBytesCT(hasher *Hasher, data []byte) int {
+	groups := (len(data) + 30) / 31
+	for i := 0; i < groups; i++ {
+		hasher.Absorb(packGroup(data, i, false))
+	}
+	hasher.Absorb(FromUint64(uint64(len(data))))
+	return groups
+}
+
+// HashLargeBytes hashes everything read from r until EOF with domain separation
+// It streams the PackBytes encoding in 31-byte groups, so the digest equals
+// HashBytes(tag, data) for the same bytes while memory use stays constant. There is
+// no MaxInputSize cap: use it for trusted local data such as files, not for input
+// from untrusted peers. Read errors other than EOF are returned
+func HashLargeBytes(tag Domain, r io.Reader) ([32]byte, error) {
+	hasher := NewHasher()
+	hasher.Absorb(FromUint64(uint64(tag)))
+	
+	br := bufio.NewReaderSize(r, MaxInputSize)
+	var group [31]byte
+	var total uint64
+	for {
+		n, err := io.ReadFull(br, group[:])
+		if n > 0 {
+			hasher.Absorb(packGroup(group[:n], 0, false))
+			total += uint64(n)
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return [32]byte{}, fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+	if total > 0 { // Matches HashBytes skipping empty chunks
+		hasher.Absorb(FromUint64(total))
+	}
+	
+	result := hasher.Finalize()
+	return result.ToBytes32(), nil
+}
+
 // HashBytesSimple is a simplified version for single byte slice
 func HashBytesSimple(tag Domain, data []byte) ([32]byte, error) {
 	return HashBytes(tag, data)
@@ -72,7 +328,9 @@ func HashBytesSimple(tag Domain, data []byte) ([32]byte, error) {
 
 // Utility functions for common operations
 
-// HashPair hashes two 32-byte values (useful for Merkle tree operations)
+// HashPair hashes two 32-byte values with Compress2, a generic two-to-one hash
+// It is not tree-compatible; MerkleNodeBytes gives the node compression used by
+// BuildMerkleTree and VerifyMerkleProof
 func HashPair(left, right [32]byte) [32]byte {
 	leftFr := FromBytes(left)
 	rightFr := FromBytes(right)
@@ -80,6 +338,15 @@ func HashPair(left, right [32]byte) [32]byte {
 	return result.ToBytes32()
 }
 
+// HashPairDomain hashes two 32-byte values under a domain tag
+// Absorbs tag, left, right in that order; the result is not commutative, so
+// swapping left and right gives a different digest. Use distinct tags to keep
+// internal-node hashing separate from leaf hashing
+func HashPairDomain(tag Domain, left, right [32]byte) [32]byte {
+	result := Hash3(FromUint64(uint64(tag)), FromBytes(left), FromBytes(right))
+	return result.ToBytes32()
+}
+
 // HashMany hashes multiple field elements with domain separation
 func HashMany(tag Domain, elements ...Fr) Fr {
 	hasher := NewHasher()
@@ -100,7 +367,9 @@ const (
 	MaxComplexityScore = 1000      // Algorithmic complexity threshold
 )
 
-// ValidateInput performs comprehensive input validation with DoS protection
+// ValidateInput enforces the size-based DoS limit
+// Poseidon2's cost is linear in input length, so content is not inspected;
+// use ValidateInputStrict to additionally apply the complexity heuristics
 func ValidateInput(data []byte) error {
 	if len(data) > MaxInputSize {
 		return errors.New("input data too large (max 64KB for DoS protection)")
@@ -110,6 +379,17 @@ func ValidateInput(data []byte) error {
 		return errors.New("input data cannot be empty")
 	}
 	
+	return nil
+}
+
+// ValidateInputStrict performs ValidateInput plus opt-in content heuristics
+// The heuristics flag low-entropy and repetitive data, which also rejects
+// legitimate inputs such as zero padding; only enable them deliberately
+func ValidateInputStrict(data []byte) error {
+	if err := ValidateInput(data); err != nil {
+		return err
+	}
+	
 	// Estimate algorithmic complexity to prevent DoS attacks
 	complexity := estimateComplexity(data)
 	if complexity > MaxComplexityScore {
@@ -119,9 +399,38 @@ func ValidateInput(data []byte) error {
 	return nil
 }
 
+// ComplexityWeights sets the penalty of each content heuristic in the complexity score
+// The size component (one point per 100 bytes) is always counted; a zero weight
+// disables its heuristic, so the zero value gives a purely size-based score
+type ComplexityWeights struct {
+	Repetition   int // Per excess repeat of a 4-byte pattern occurring more than 10 times
+	LowDiversity int // Fewer than 4 distinct byte values in more than 32 bytes
+	DominantByte int // One byte value making up more than half of more than 16 bytes
+	NullBytes    int // More than a third of the bytes are 0x00
+	MaxBytes     int // More than a third of the bytes are 0xFF
+}
+
+// DefaultComplexityWeights returns the weights used by ValidateInputStrict
+func DefaultComplexityWeights() ComplexityWeights {
+	return ComplexityWeights{
+		Repetition:   1,
+		LowDiversity: 50,
+		DominantByte: 30,
+		NullBytes:    20,
+		MaxBytes:     20,
+	}
+}
+
 // estimateComplexity analyzes input data to detect potential DoS attack vectors
-// Returns a complexity score based on various factors
+// Returns a complexity score based on various factors, using the default weights
 func estimateComplexity(data []byte) int {
+	return EstimateComplexityWithWeights(data, DefaultComplexityWeights())
+}
+
+// EstimateComplexityWithWeights computes the ValidateInputStrict complexity score
+// of data with custom heuristic weights, so deployments can tune or disable them;
+// compare the result against MaxComplexityScore or a threshold of their own
+func EstimateComplexityWithWeights(data []byte, w ComplexityWeights) int {
 	if len(data) == 0 {
 		return 0
 	}
@@ -134,22 +443,22 @@ func estimateComplexity(data []byte) int {
 	// Pattern analysis for potential DoS vectors
 	
 	// 1. Repetitive patterns (could cause algorithmic issues)
-	patternComplexity := analyzePatterns(data)
+	patternComplexity := analyzePatterns(data, w)
 	complexity += patternComplexity
 	
 	// 2. High entropy sequences (could indicate crafted input)
-	entropyComplexity := analyzeEntropy(data)
+	entropyComplexity := analyzeEntropy(data, w)
 	complexity += entropyComplexity
 	
 	// 3. Edge case byte values (null bytes, max values)
-	edgeComplexity := analyzeEdgeCases(data)
+	edgeComplexity := analyzeEdgeCases(data, w)
 	complexity += edgeComplexity
 	
 	return complexity
 }
 
 // analyzePatterns detects repetitive patterns that could cause performance issues
-func analyzePatterns(data []byte) int {
+func analyzePatterns(data []byte, w ComplexityWeights) int {
 	if len(data) < 4 {
 		return 0
 	}
@@ -166,7 +475,7 @@ func analyzePatterns(data []byte) int {
 	// High repetition of patterns increases complexity score
 	for _, count := range patternCounts {
 		if count > 10 { // Pattern repeats more than 10 times
-			complexity += count - 10 // Penalty for excessive repetition
+			complexity += (count - 10) * w.Repetition // Penalty for excessive repetition
 		}
 	}
 	
@@ -174,7 +483,7 @@ func analyzePatterns(data []byte) int {
 }
 
 // analyzeEntropy checks for unusual entropy characteristics
-func analyzeEntropy(data []byte) int {
+func analyzeEntropy(data []byte, w ComplexityWeights) int {
 	if len(data) < 16 {
 		return 0
 	}
@@ -201,19 +510,19 @@ func analyzeEntropy(data []byte) int {
 	
 	// Very low diversity (few unique bytes) could indicate crafted input
 	if nonZeroBytes < 4 && len(data) > 32 {
-		complexity += 50
+		complexity += w.LowDiversity
 	}
 	
 	// Very high frequency of single byte could indicate crafted input
 	if maxFreq > len(data)/2 && len(data) > 16 {
-		complexity += 30
+		complexity += w.DominantByte
 	}
 	
 	return complexity
 }
 
 // analyzeEdgeCases detects potentially problematic byte sequences
-func analyzeEdgeCases(data []byte) int {
+func analyzeEdgeCases(data []byte, w ComplexityWeights) int {
 	complexity := 0
 	nullBytes := 0
 	maxBytes := 0
@@ -228,10 +537,10 @@ func analyzeEdgeCases(data []byte) int {
 	
 	// High concentration of null or max bytes could indicate crafted input
 	if nullBytes > len(data)/3 {
-		complexity += 20
+		complexity += w.NullBytes
 	}
 	if maxBytes > len(data)/3 {
-		complexity += 20
+		complexity += w.MaxBytes
 	}
 	
 	return complexity
@@ -0,0 +1,85 @@
+package poseidon2
+
+import (
+	"encoding/binary"
+	"math/big"
+	"math/bits"
+)
+
+// barrettMu is floor(2^512 / r) as five little-endian limbs (about 2^258.4)
+var barrettMu = computeBarrettMu()
+
+// computeBarrettMu derives the Barrett constant from the modulus
+func computeBarrettMu() [5]uint64 {
+	mu := new(big.Int).Lsh(big.NewInt(1), 512)
+	mu.Quo(mu, limbsToBigInt(&rModulus))
+	
+	var b [40]byte
+	mu.FillBytes(b[:])
+	var limbs [5]uint64
+	for i := range limbs {
+		limbs[i] = binary.BigEndian.Uint64(b[32-8*i : 40-8*i])
+	}
+	return limbs
+}
+
+// mulLimbs sets dst = a * b by schoolbook multiplication; len(dst) must be len(a)+len(b)
+func mulLimbs(dst, a, b []uint64) {
+	for i := range dst {
+		dst[i] = 0
+	}
+	for i := range a {
+		var carry uint64
+		for j := range b {
+			hi, lo := bits.Mul64(a[i], b[j])
+			sum, c1 := bits.Add64(dst[i+j], lo, 0)
+			sum, c2 := bits.Add64(sum, carry, 0)
+			dst[i+j] = sum
+			carry = hi + c1 + c2
+		}
+		dst[i+len(b)] = carry
+	}
+}
+
+// MulBarrett performs z = x*y mod r on canonical values in regular (non-Montgomery) form
+// It is an independent second implementation of field multiplication using Barrett
+// reduction with mu = floor(2^512/r), intended for differential testing of the
+// Montgomery path and for callers working with plain integers. x and y must be
+// canonical (< r), in which case the result is canonical. Do NOT pass Montgomery
+// elements such as FromUint64 output: MulBarrett(x*R, y*R) is x*y*R^2, not x*y*R
+func (z *Fr) MulBarrett(x, y *Fr) *Fr {
+	// Full 512-bit product
+	var p [8]uint64
+	mulLimbs(p[:], x[:], y[:])
+	
+	// q = floor(floor(p / 2^192) * mu / 2^320) underestimates p/r by at most 2
+	var q2 [10]uint64
+	mulLimbs(q2[:], p[3:8], barrettMu[:])
+	q3 := q2[5:10]
+	
+	// rem = (p - q*r) mod 2^320, computed on the low five words only
+	var qr [9]uint64
+	mulLimbs(qr[:], q3, rModulus[:])
+	var rem [5]uint64
+	var borrow uint64
+	for i := 0; i < 5; i++ {
+		rem[i], borrow = bits.Sub64(p[i], qr[i], borrow)
+	}
+	
+	// rem < 3r: two constant-time conditional subtractions of r
+	for k := 0; k < 2; k++ {
+		var diff [5]uint64
+		borrow = 0
+		for i := 0; i < 4; i++ {
+			diff[i], borrow = bits.Sub64(rem[i], rModulus[i], borrow)
+		}
+		diff[4], borrow = bits.Sub64(rem[4], 0, borrow)
+		mask := borrow - 1 // All ones when rem >= r
+		for i := 0; i < 5; i++ {
+			rem[i] ^= (rem[i] ^ diff[i]) & mask
+		}
+	}
+	
+	z[0], z[1], z[2], z[3] = rem[0], rem[1], rem[2], rem[3]
+	return z
+}
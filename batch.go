@@ -0,0 +1,47 @@
+package poseidon2
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// HashBytesBatch computes HashBytes(tag, inputs[i]) for every input on a pool of workers
+// Output i corresponds to input i regardless of scheduling. Every input is first
+// checked with ValidateInput, and nothing is hashed if one fails; the error names
+// its index. workers <= 0 uses GOMAXPROCS, and never more workers than inputs run
+func HashBytesBatch(tag Domain, inputs [][]byte, workers int) ([][32]byte, error) {
+	for i, data := range inputs {
+		if err := ValidateInput(data); err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(inputs) {
+		workers = len(inputs)
+	}
+
+	out := make([][32]byte, len(inputs))
+	var next atomic.Int64 // Index of the next unclaimed input
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= len(inputs) {
+					return
+				}
+				out[i] = HashBytesCT(tag, inputs[i]) // Same digest as HashBytes, which cannot fail here
+			}
+		}()
+	}
+	wg.Wait()
+
+	return out, nil
+}
@@ -0,0 +1,15 @@
+package poseidon2
+
+// Commit returns a commitment to value under randomness, computed as Hash(randomness, value)
+// Binding follows from collision resistance of the sponge. Hiding holds only if
+// randomness is uniformly random, secret, and never reused for another value
+func Commit(value Fr, randomness Fr) Fr {
+	return Hash2(randomness, value)
+}
+
+// Open reports whether commitment opens to value under randomness
+// The recomputed commitment depends on secret randomness, so it is compared in constant time
+func Open(commitment, value, randomness Fr) bool {
+	expected := Commit(value, randomness)
+	return expected.EqualCT(&commitment)
+}
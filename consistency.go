@@ -0,0 +1,107 @@
+package poseidon2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Consistency proofs show that a tree of newSize leaves extends a tree of
+// oldSize leaves (the first oldSize leaves are identical), in the spirit of
+// Certificate Transparency (RFC 6962) adapted to zero-padded perfect trees.
+//
+// Let N = oldSize. The proof walks the path of leaf position N in the new tree:
+//
+//	proof[0]   = new leaf N (the first leaf the old tree does not contain)
+//	proof[h+1] = sibling of the level-h node holding position N, for h = 0..depth(new)-1
+//
+// When bit h of N is set the sibling is a left node lying entirely inside
+// [0, N), which both trees share. Otherwise it is a right node which in the
+// old tree is the all-zero padding subtree. The verifier recomputes both
+// roots from the same shared nodes, binding them to identical prefixes.
+//
+// Because padding leaves are Zero(), a root does not commit to the tree size;
+// sizes must be authenticated alongside the roots they describe.
+
+// ConsistencyProof proves that new extends old
+func ConsistencyProof(old, new *MerkleTree) ([][32]byte, error) {
+	if old == nil || new == nil {
+		return nil, errors.New("consistency proof requires two trees")
+	}
+	if old.size > new.size {
+		return nil, fmt.Errorf("old tree has more leaves than new tree (%d > %d)", old.size, new.size)
+	}
+	for i := 0; i < old.size; i++ {
+		if !old.levels[0][i].Equal(&new.levels[0][i]) {
+			return nil, fmt.Errorf("trees diverge at leaf %d", i)
+		}
+	}
+
+	if old.size == new.size {
+		return [][32]byte{}, nil
+	}
+
+	n := old.size
+	proof := make([][32]byte, 0, new.Depth()+1)
+	proof = append(proof, new.levels[0][n].ToBytes32())
+	for h := 0; h < new.Depth(); h++ {
+		pos := n >> h
+		proof = append(proof, new.levels[h][pos^1].ToBytes32())
+	}
+	return proof, nil
+}
+
+// VerifyConsistency checks a proof produced by ConsistencyProof
+func VerifyConsistency(oldRoot, newRoot [32]byte, oldSize, newSize int, proof [][32]byte) bool {
+	if oldSize <= 0 || newSize < oldSize {
+		return false
+	}
+	if oldSize == newSize {
+		return len(proof) == 0 && oldRoot == newRoot
+	}
+
+	oldDepth := treeDepth(oldSize)
+	newDepth := treeDepth(newSize)
+	if len(proof) != newDepth+1 {
+		return false
+	}
+
+	oldNode := Zero() // Position oldSize is padding in the old tree
+	newNode := FromBytes(proof[0])
+	zero := Zero() // Root of an all-padding subtree at the current level
+
+	var oldComputed Fr
+	for h := 0; h < newDepth; h++ {
+		sibling := FromBytes(proof[h+1])
+		shared := (oldSize>>h)&1 == 1
+
+		if h == oldDepth {
+			// A full old tree is exactly the shared left sibling at its root level
+			if shared {
+				oldComputed = sibling
+			} else {
+				oldComputed = oldNode
+			}
+		}
+
+		if shared {
+			if h < oldDepth {
+				oldNode = MerkleNode(sibling, oldNode)
+			}
+			newNode = MerkleNode(sibling, newNode)
+		} else {
+			if h < oldDepth {
+				oldNode = MerkleNode(oldNode, zero)
+			}
+			newNode = MerkleNode(newNode, sibling)
+		}
+
+		if h < oldDepth {
+			zero = MerkleNode(zero, zero)
+		}
+	}
+	if oldDepth == newDepth {
+		oldComputed = oldNode
+	}
+
+	return oldComputed.ToBytes32() == oldRoot && newNode.ToBytes32() == newRoot
+}
@@ -18,4 +18,6 @@ const (
 	DomainPolicyRoot  Domain = 0x53475052 // "SGPR"
 	DomainFSChallenge Domain = 0x53474653 // "SGFS"
 	DomainTapTweak    Domain = 0x53475454 // "SGTT"
+	DomainMerkleLeaf  Domain = 0x53474d4c // "SGML"
+	DomainMerkleNode  Domain = 0x53474d4e // "SGMN"
 )
\ No newline at end of file
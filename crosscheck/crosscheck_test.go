@@ -0,0 +1,83 @@
+package crosscheck
+
+// Cross-implementation check of the scalar field against gnark-crypto's bn254 fr
+// package. It lives in its own module so the root module stays free of
+// dependencies; run it from this directory with go test ./...
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+	
+	"github.com/afsheenb/poseidon2"
+	"github.com/consensys/gnark-crypto/ecc/bn254/fr"
+)
+
+// toGnark converts through the canonical big-endian encoding
+func toGnark(x poseidon2.Fr) fr.Element {
+	var e fr.Element
+	b := x.ToBytes32()
+	e.SetBytes(b[:])
+	return e
+}
+
+// randomFr returns a uniformly random canonical element
+func randomFr(rng *rand.Rand) poseidon2.Fr {
+	return poseidon2.FromBigInt(new(big.Int).Rand(rng, fr.Modulus()))
+}
+
+// TestCrossLibraryField checks conversions and arithmetic against gnark-crypto
+func TestCrossLibraryField(t *testing.T) {
+	// The moduli agree exactly when r reduces to zero and r-1 to minus one
+	modulus := fr.Modulus()
+	if r := poseidon2.FromBigInt(modulus); !r.IsZero() {
+		t.Fatal("gnark-crypto's modulus does not reduce to zero")
+	}
+	if rm1 := poseidon2.FromBigInt(new(big.Int).Sub(modulus, big.NewInt(1))); !rm1.IsMinusOne() {
+		t.Fatal("gnark-crypto's modulus minus one is not -1")
+	}
+	
+	rng := rand.New(rand.NewSource(85))
+	for i := 0; i < 10000; i++ {
+		// Both libraries use Montgomery form with R = 2^256, so the limbs must agree
+		u := rng.Uint64()
+		var eu fr.Element
+		eu.SetUint64(u)
+		if fu := poseidon2.FromUint64(u); [4]uint64(fu) != [4]uint64(eu) {
+			t.Fatalf("FromUint64(%d) limbs = %x, gnark-crypto has %x", u, [4]uint64(fu), [4]uint64(eu))
+		}
+		
+		a, b := randomFr(rng), randomFr(rng)
+		ea, eb := toGnark(a), toGnark(b)
+		if [4]uint64(a) != [4]uint64(ea) {
+			t.Fatalf("limbs of %s differ from gnark-crypto", a.Hex())
+		}
+		
+		var sum, diff, prod, inv poseidon2.Fr
+		var esum, ediff, eprod, einv fr.Element
+		sum.Add(&a, &b)
+		esum.Add(&ea, &eb)
+		diff.Sub(&a, &b)
+		ediff.Sub(&ea, &eb)
+		prod.Mul(&a, &b)
+		eprod.Mul(&ea, &eb)
+		inv.Inverse(&a)
+		einv.Inverse(&ea)
+		
+		checks := []struct {
+			op   string
+			got  poseidon2.Fr
+			want fr.Element
+		}{
+			{"Add", sum, esum},
+			{"Sub", diff, ediff},
+			{"Mul", prod, eprod},
+			{"Inverse", inv, einv},
+		}
+		for _, c := range checks {
+			if c.got.ToBytes32() != c.want.Bytes() {
+				t.Fatalf("%s(%s, %s) = %s, gnark-crypto has %s", c.op, a.Hex(), b.Hex(), c.got.Hex(), c.want.String())
+			}
+		}
+	}
+}
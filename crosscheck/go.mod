@@ -0,0 +1,18 @@
+module github.com/afsheenb/poseidon2/crosscheck
+
+go 1.23
+
+require (
+	github.com/afsheenb/poseidon2 v0.0.0
+	github.com/consensys/gnark-crypto v0.14.0
+)
+
+require (
+	github.com/bits-and-blooms/bitset v1.14.2 // indirect
+	github.com/consensys/bavard v0.1.13 // indirect
+	github.com/mmcloughlin/addchain v0.4.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	rsc.io/tmplfunc v0.0.3 // indirect
+)
+
+replace github.com/afsheenb/poseidon2 => ../
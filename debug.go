@@ -0,0 +1,13 @@
+package poseidon2
+
+import "fmt"
+
+// assertValid panics if any operand of op is not canonical
+// Only reachable when built with the poseidon2_debug tag (see debugChecks)
+func assertValid(op string, operands ...*Fr) {
+	for i, x := range operands {
+		if !x.IsValid() {
+			panic(fmt.Sprintf("poseidon2: %s operand %d is not reduced mod r: %s", op, i, limbsToBigInt(x)))
+		}
+	}
+}
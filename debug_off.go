@@ -0,0 +1,7 @@
+//go:build !poseidon2_debug
+
+package poseidon2
+
+// debugChecks enables canonical-input assertions in Add, Sub and Mul
+// Build with -tags poseidon2_debug to turn them on
+const debugChecks = false
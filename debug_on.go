@@ -0,0 +1,6 @@
+//go:build poseidon2_debug
+
+package poseidon2
+
+// debugChecks enables canonical-input assertions in Add, Sub and Mul
+const debugChecks = true
@@ -0,0 +1,41 @@
+//go:build poseidon2_debug
+
+package poseidon2
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestDebugAssertions checks non-canonical operands panic under poseidon2_debug
+func TestDebugAssertions(t *testing.T) {
+	bad := rModulus // Raw limbs equal to r, not reduced
+	good := FromUint64(5)
+	
+	ops := map[string]func(z, x, y *Fr){
+		"Add": func(z, x, y *Fr) { z.Add(x, y) },
+		"Sub": func(z, x, y *Fr) { z.Sub(x, y) },
+		"Mul": func(z, x, y *Fr) { z.Mul(x, y) },
+	}
+	for name, op := range ops {
+		for _, operands := range [][2]*Fr{{&bad, &good}, {&good, &bad}} {
+			func() {
+				defer func() {
+					r := recover()
+					if r == nil {
+						t.Errorf("%s with a non-canonical operand did not panic", name)
+						return
+					}
+					if msg, ok := r.(string); !ok || !strings.Contains(msg, name) {
+						t.Errorf("%s panicked with unexpected value %v", name, r)
+					}
+				}()
+				var z Fr
+				op(&z, operands[0], operands[1])
+			}()
+		}
+		
+		var z Fr
+		op(&z, &good, &good) // Canonical operands must not panic
+	}
+}
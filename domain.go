@@ -0,0 +1,76 @@
+package poseidon2
+
+import (
+	"fmt"
+	"sync"
+)
+
+// domainRegistry maps names to domain tags; built-in tags are pre-registered
+var domainRegistry = struct {
+	sync.RWMutex
+	byName map[string]Domain
+	byTag  map[Domain]string
+}{
+	byName: make(map[string]Domain),
+	byTag:  make(map[Domain]string),
+}
+
+// builtinDomains lists the package's own tags, reserved at init
+var builtinDomains = []struct {
+	name string
+	tag  Domain
+}{
+	{"generic", DomainGeneric},
+	{"poet-node", DomainPOETNode},
+	{"policy-root", DomainPolicyRoot},
+	{"fs-challenge", DomainFSChallenge},
+	{"tap-tweak", DomainTapTweak},
+	{"merkle-leaf", DomainMerkleLeaf},
+	{"merkle-node", DomainMerkleNode},
+	{"rand", domainRand},
+	{"roots", domainRoots},
+	{"label", domainLabel},
+	{"multiset", domainMultiset},
+	{"set", domainSet},
+	{"var-uints", domainVarUints},
+	{"counted", domainCounted},
+}
+
+func init() {
+	for _, d := range builtinDomains {
+		if err := RegisterDomain(d.name, d.tag); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterDomain reserves tag under name for application use
+// Fails if either the name or the tag is already taken, including by a built-in domain
+func RegisterDomain(name string, tag Domain) error {
+	if name == "" {
+		return fmt.Errorf("domain name cannot be empty")
+	}
+	
+	domainRegistry.Lock()
+	defer domainRegistry.Unlock()
+	
+	if existing, ok := domainRegistry.byName[name]; ok {
+		return fmt.Errorf("domain name %q already registered with tag 0x%08x", name, uint64(existing))
+	}
+	if owner, ok := domainRegistry.byTag[tag]; ok {
+		return fmt.Errorf("domain tag 0x%08x already registered as %q", uint64(tag), owner)
+	}
+	
+	domainRegistry.byName[name] = tag
+	domainRegistry.byTag[tag] = name
+	return nil
+}
+
+// LookupDomain returns the tag registered under name
+func LookupDomain(name string) (Domain, bool) {
+	domainRegistry.RLock()
+	defer domainRegistry.RUnlock()
+	
+	tag, ok := domainRegistry.byName[name]
+	return tag, ok
+}
@@ -0,0 +1,85 @@
+package poseidon2
+
+// Duplex is a duplex sponge over the Poseidon2 permutation with rate T-1
+// Unlike Hasher, absorbing and squeezing may be interleaved freely and every call
+// is bound into the state immediately. The construction is, with R = T-1:
+//
+//   - NewDuplex(tag) starts from Perm([0, ..., 0, tag]), so the tag is bound by a
+//     permutation before any frame value is added to the capacity
+//   - Absorb(in) splits in into blocks of at most R elements (an empty input is one
+//     empty block). For each block, element i is added to state[i], a frame value
+//     is added to the capacity state[T-1], and the state is permuted. The last block
+//     of the call, holding k elements, uses the frame value k; every earlier block is
+//     full and uses R+2, marking that the same call continues
+//   - Squeeze(n) adds the frame value R+1 to the capacity, permutes, and outputs
+//     state[0..R); while more output is needed it adds R+1 and permutes again
+//
+// Every call therefore permutes at least once, and the frame values (0..R for the
+// last absorb block, R+1 for squeezes, R+2 for continued absorb blocks) keep
+// absorbed lengths and call boundaries distinct: Absorb([x]) differs from
+// Absorb([x, 0]) and from Squeeze, and Absorb([a, b, c]) differs from
+// Absorb([a, b]) followed by Absorb([c]). Adding frames to a raw tag would let
+// tag+k collide with tag'+k', so the initial permutation is what separates tags.
+// A Duplex is not safe for concurrent use
+type Duplex struct {
+	state [T]Fr
+}
+
+// Capacity frame values beyond the final-block lengths 0..R
+const (
+	duplexSqueezeFrame  = T     // A squeeze permutation, R+1
+	duplexContinueFrame = T + 1 // A full absorb block followed by more of the same call, R+2
+)
+
+// NewDuplex creates a duplex sponge starting from the permuted state [0, ..., 0, tag]
+func NewDuplex(tag Domain) *Duplex {
+	d := &Duplex{}
+	d.state[T-1] = FromUint64(uint64(tag))
+	ProductionPermutation(&d.state)
+	return d
+}
+
+// frame adds the frame value to the capacity and permutes the state
+func (d *Duplex) frame(value uint64) {
+	f := FromUint64(value)
+	d.state[T-1].Add(&d.state[T-1], &f)
+	ProductionPermutation(&d.state)
+}
+
+// Absorb mixes in into the rate, permuting once per block of T-1 elements
+func (d *Duplex) Absorb(in []Fr) {
+	for {
+		k := len(in)
+		if k > T-1 {
+			k = T - 1
+		}
+		for i := 0; i < k; i++ {
+			d.state[i].Add(&d.state[i], &in[i])
+		}
+		
+		in = in[k:]
+		if len(in) == 0 {
+			d.frame(uint64(k))
+			return
+		}
+		d.frame(duplexContinueFrame)
+	}
+}
+
+// Squeeze permutes and returns n elements, permuting again every T-1 elements
+// Squeeze(0) (or a negative n) still performs one permutation and returns an empty slice
+func (d *Duplex) Squeeze(n int) []Fr {
+	if n < 0 {
+		n = 0
+	}
+	out := make([]Fr, 0, n)
+	for {
+		d.frame(duplexSqueezeFrame)
+		for i := 0; i < T-1 && len(out) < n; i++ {
+			out = append(out, d.state[i])
+		}
+		if len(out) == n {
+			return out
+		}
+	}
+}
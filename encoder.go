@@ -0,0 +1,45 @@
+package poseidon2
+
+// FieldEncoder is implemented by records that can be hashed with HashStruct
+// EncodeFields must return the same elements in the same order for equal records;
+// field order is part of the hash, so reordering fields changes the digest
+type FieldEncoder interface {
+	EncodeFields() []Fr
+}
+
+// HashStruct hashes a record under a domain tag
+// The tag is absorbed first, then the encoded fields, exactly as HashMany(tag, fields...)
+func HashStruct(tag Domain, v FieldEncoder) Fr {
+	return HashMany(tag, v.EncodeFields()...)
+}
+
+// FieldList is a ready-made FieldEncoder over a fixed list of elements
+type FieldList []Fr
+
+// EncodeFields returns the list itself
+func (l FieldList) EncodeFields() []Fr {
+	return l
+}
+
+// Uint64Fields encodes integer fields (counters, indices, flags) in order
+func Uint64Fields(values ...uint64) FieldList {
+	fields := make(FieldList, len(values))
+	for i, v := range values {
+		fields[i] = FromUint64(v)
+	}
+	return fields
+}
+
+// BytesFields encodes short byte fields (digests, keys, identifiers) of up to 32 bytes each
+// Each field is converted with FromBytesN, so it is right-aligned and reduced mod r
+func BytesFields(values ...[]byte) (FieldList, error) {
+	fields := make(FieldList, len(values))
+	for i, v := range values {
+		f, err := FromBytesN(v)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = f
+	}
+	return fields, nil
+}
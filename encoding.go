@@ -0,0 +1,59 @@
+package poseidon2
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// MarshalText implements encoding.TextMarshaler as the canonical 0x-prefixed hex of Hex
+func (f Fr) MarshalText() ([]byte, error) {
+	return []byte(f.Hex()), nil
+}
+
+// MarshalJSON implements json.Marshaler, encoding f as a JSON string of its hex form
+// so []Fr encodes as an array of hex strings that UnmarshalJSON reads back
+func (f Fr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Hex())
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+// Accepts the same forms as ParseField: decimal, or hex with a 0x prefix
+func (z *Fr) UnmarshalText(text []byte) error {
+	f, err := ParseField(string(text))
+	if err != nil {
+		return err
+	}
+	*z = f
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+// A field element may be a JSON string (any form UnmarshalText accepts) or, for
+// hand-written configs, a bare non-negative integer that fits in a uint64. Larger
+// values, negatives and fractional or exponent numbers must be given as strings
+func (z *Fr) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil // Leave z unchanged, as encoding/json does for null
+	}
+	
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return z.UnmarshalText([]byte(s))
+	}
+	
+	if bytes.ContainsAny(data, ".eE") {
+		return fmt.Errorf("field element %s is not an integer; use a quoted decimal or hex string", data)
+	}
+	v, err := strconv.ParseUint(string(data), 10, 64)
+	if err != nil {
+		return fmt.Errorf("field element %s is not a uint64 JSON number; use a quoted decimal or hex string", data)
+	}
+	*z = FromUint64(v)
+	return nil
+}
@@ -2,7 +2,11 @@ package poseidon2
 
 import (
 	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
 	"math/bits"
+	"strings"
 )
 
 // Fr represents a field element in Montgomery form for bn256-r field
@@ -51,6 +55,8 @@ func One() Fr {
 }
 
 // FromUint64 converts a uint64 to Montgomery form
+// The conversion is a single Mul by montgomeryR2; Mul never calls back into
+// FromUint64, so there is no recursion and every uint64 (always < r) is exact
 func FromUint64(x uint64) Fr {
 	if x == 0 {
 		return Zero()
@@ -67,8 +73,67 @@ func FromUint64(x uint64) Fr {
 	return result
 }
 
+// FromInt64 converts a signed integer to Montgomery form
+// Non-negative x is FromUint64(x); negative x maps to the field negation r - |x|,
+// so FromInt64(-1) is r - 1 and FromInt64(a) + FromInt64(b) = FromInt64(a + b)
+// whenever a + b does not overflow. Circuits reproduce it as x mod r
+func FromInt64(x int64) Fr {
+	if x >= 0 {
+		return FromUint64(uint64(x))
+	}
+	magnitude := FromUint64(-uint64(x)) // Two's complement negation, exact for math.MinInt64
+	var result Fr
+	result.Neg(&magnitude)
+	return result
+}
+
+// SetUint64 sets z to the Montgomery form of x and returns z
+// It is the in-place form of FromUint64 for loops that reuse one variable
+func (z *Fr) SetUint64(x uint64) *Fr {
+	*z = FromUint64(x)
+	return z
+}
+
+// FromUint64Checked converts x like FromUint64 and cross-checks the Montgomery
+// result against x*2^256 mod r computed with big.Int
+// An error means the Montgomery constants are corrupt and no result can be trusted
+func FromUint64Checked(x uint64) (Fr, error) {
+	result := FromUint64(x)
+	
+	expected := new(big.Int).Lsh(new(big.Int).SetUint64(x), 256)
+	expected.Mod(expected, limbsToBigInt(&rModulus))
+	if limbsToBigInt(&result).Cmp(expected) != 0 {
+		return Fr{}, fmt.Errorf("montgomery conversion of %d is inconsistent with big.Int reference", x)
+	}
+	
+	return result, nil
+}
+
+// limbsToBigInt interprets the raw limbs as an integer without leaving Montgomery form
+func limbsToBigInt(f *Fr) *big.Int {
+	var b [32]byte
+	binary.BigEndian.PutUint64(b[24:32], f[0])
+	binary.BigEndian.PutUint64(b[16:24], f[1])
+	binary.BigEndian.PutUint64(b[8:16], f[2])
+	binary.BigEndian.PutUint64(b[0:8], f[3])
+	return new(big.Int).SetBytes(b[:])
+}
+
+// FromMontgomeryLimbs builds an element from limbs ALREADY IN MONTGOMERY FORM
+// The limbs are little-endian and are taken as x*R mod r, NOT as the integer x:
+// FromMontgomeryLimbs([4]uint64{100, 0, 0, 0}) is not 100. Use FromUint64 or
+// FromBytes for plain integers. Limbs >= r are rejected rather than reduced
+func FromMontgomeryLimbs(limbs [4]uint64) (Fr, error) {
+	f := Fr(limbs)
+	var temp Fr
+	if borrow := temp.sub(&f, &rModulus); borrow == 0 {
+		return Fr{}, fmt.Errorf("montgomery limbs %x are not below the modulus", limbs)
+	}
+	return f, nil
+}
 
 // FromBytes converts a 32-byte big-endian representation to Montgomery form
+// data[0] is the most significant byte; see FromBytesLE for little-endian input
 func FromBytes(data [32]byte) Fr {
 	// Convert big-endian bytes to limbs (little-endian)
 	limbs := Fr{
@@ -83,16 +148,17 @@ func FromBytes(data [32]byte) Fr {
 	
 	// Convert to Montgomery form
 	var result Fr
-	result.Mul(&limbs, &montgomeryR2)
+	result.MulCIOS(&limbs, &montgomeryR2) // Accepts limbs >= r, so bypass the debug assertion
 	return result
 }
 
 // ToBytes32 converts from Montgomery form to 32-byte big-endian representation
+// result[0] is the most significant byte; see ToBytesLE for little-endian output
 func (f Fr) ToBytes32() [32]byte {
 	// Convert from Montgomery form to regular form
 	one := Fr{1, 0, 0, 0}
 	var regular Fr
-	regular.Mul(&f, &one) // f * 1 * R^(-1) = f * R^(-1) = regular form
+	regular.MulCIOS(&f, &one) // f * 1 * R^(-1) = f * R^(-1) = regular form
 	
 	var result [32]byte
 	binary.BigEndian.PutUint64(result[24:32], regular[0]) // least significant
@@ -103,9 +169,159 @@ func (f Fr) ToBytes32() [32]byte {
 	return result
 }
 
+// ToBytes32Batch converts many elements to canonical 32-byte big-endian form
+// Equivalent to calling ToBytes32 on each element, with one output allocation
+func ToBytes32Batch(elements []Fr) [][32]byte {
+	result := make([][32]byte, len(elements))
+	one := Fr{1, 0, 0, 0}
+	
+	var regular Fr
+	for i := range elements {
+		regular.MulCIOS(&elements[i], &one)
+		binary.BigEndian.PutUint64(result[i][24:32], regular[0]) // least significant
+		binary.BigEndian.PutUint64(result[i][16:24], regular[1])
+		binary.BigEndian.PutUint64(result[i][8:16], regular[2])
+		binary.BigEndian.PutUint64(result[i][0:8], regular[3]) // most significant
+	}
+	
+	return result
+}
+
+// FromBytesN converts up to 32 big-endian bytes to Montgomery form
+// Shorter input is left-padded with zeros (right-aligned), so an empty slice is zero;
+// values >= r are reduced
+func FromBytesN(b []byte) (Fr, error) {
+	if len(b) > 32 {
+		return Fr{}, fmt.Errorf("field element input too long: %d bytes (max 32)", len(b))
+	}
+	
+	var padded [32]byte
+	copy(padded[32-len(b):], b)
+	return FromBytes(padded), nil
+}
+
+// ReduceWide interprets b as a big-endian integer of any length and reduces it mod r
+// For uniform k-bit input the output's distance from uniform is about r/2^k, so use
+// at least 48 bytes (e.g. a SHA-512 digest) when a near-uniform element is needed;
+// 32 bytes of uniform input is noticeably biased towards small values
+func ReduceWide(b []byte) Fr {
+	return FromBigInt(new(big.Int).SetBytes(b))
+}
+
+// FromBytesLE converts a 32-byte little-endian representation to Montgomery form
+// data[0] is the least significant byte; values >= r are reduced
+func FromBytesLE(data [32]byte) Fr {
+	limbs := Fr{
+		binary.LittleEndian.Uint64(data[0:8]), // least significant
+		binary.LittleEndian.Uint64(data[8:16]),
+		binary.LittleEndian.Uint64(data[16:24]),
+		binary.LittleEndian.Uint64(data[24:32]), // most significant
+	}
+	
+	limbs.reduce()
+	
+	var result Fr
+	result.MulCIOS(&limbs, &montgomeryR2) // Accepts limbs >= r, so bypass the debug assertion
+	return result
+}
+
+// ToBytesLE converts from Montgomery form to 32-byte little-endian representation
+// result[0] is the least significant byte
+func (f Fr) ToBytesLE() [32]byte {
+	one := Fr{1, 0, 0, 0}
+	var regular Fr
+	regular.MulCIOS(&f, &one)
+	
+	var result [32]byte
+	binary.LittleEndian.PutUint64(result[0:8], regular[0]) // least significant
+	binary.LittleEndian.PutUint64(result[8:16], regular[1])
+	binary.LittleEndian.PutUint64(result[16:24], regular[2])
+	binary.LittleEndian.PutUint64(result[24:32], regular[3]) // most significant
+	
+	return result
+}
+
+// ToBigInt returns the canonical integer value of the field element
+func (f Fr) ToBigInt() *big.Int {
+	b := f.ToBytes32()
+	return new(big.Int).SetBytes(b[:])
+}
+
+// FromBigInt converts an integer to Montgomery form, reducing it mod r
+// Negative values map to their field negation (r - |x| mod r)
+func FromBigInt(x *big.Int) Fr {
+	reduced := new(big.Int).Mod(x, limbsToBigInt(&rModulus))
+	
+	var data [32]byte
+	reduced.FillBytes(data[:])
+	return FromBytes(data)
+}
+
+// ParseField parses a field element from a decimal string or a 0x-prefixed hex string
+// The value is reduced mod r; a leading '-' negates it in the field
+func ParseField(s string) (Fr, error) {
+	digits := strings.TrimPrefix(s, "-")
+	base := 10
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits = digits[2:]
+		base = 16
+	}
+	if digits == "" || strings.HasPrefix(digits, "+") || strings.HasPrefix(digits, "-") {
+		return Fr{}, fmt.Errorf("invalid field element %q", s)
+	}
+	
+	x, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return Fr{}, fmt.Errorf("invalid field element %q", s)
+	}
+	if strings.HasPrefix(s, "-") {
+		x.Neg(x)
+	}
+	return FromBigInt(x), nil
+}
+
+// SetHex sets z from a big-endian hex string with an optional 0x prefix
+// Strings shorter than 64 digits are left-padded with zeros and the value is
+// reduced mod r, as in FromBytes. Empty, over-long or non-hex input is rejected
+// and leaves z unchanged
+func (z *Fr) SetHex(s string) error {
+	digits := s
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0X") {
+		digits = digits[2:]
+	}
+	if digits == "" {
+		return fmt.Errorf("invalid hex field element %q: no digits", s)
+	}
+	if len(digits) > 64 {
+		return fmt.Errorf("invalid hex field element %q: %d digits exceeds 64", s, len(digits))
+	}
+	
+	var data [32]byte
+	padded := strings.Repeat("0", 64-len(digits)) + digits
+	if _, err := hex.Decode(data[:], []byte(padded)); err != nil {
+		return fmt.Errorf("invalid hex field element %q: %w", s, err)
+	}
+	*z = FromBytes(data)
+	return nil
+}
+
+// String returns the canonical decimal representation, implementing fmt.Stringer
+func (f Fr) String() string {
+	return f.ToBigInt().String()
+}
+
+// Hex returns the canonical value as 0x-prefixed, zero-padded 64-digit hex
+func (f Fr) Hex() string {
+	b := f.ToBytes32()
+	return "0x" + hex.EncodeToString(b[:])
+}
+
 // Mul performs Montgomery multiplication: (a * b * R^(-1)) mod r
 // Uses CIOS (Coarsely Integrated Operand Scanning) algorithm for constant-time operations
 func (z *Fr) Mul(x, y *Fr) *Fr {
+	if debugChecks {
+		assertValid("Mul", x, y)
+	}
 	return z.MulCIOS(x, y)
 }
 
@@ -168,6 +384,9 @@ func (z *Fr) MulCIOS(x, y *Fr) *Fr {
 
 // Add performs field addition: (a + b) mod r
 func (z *Fr) Add(x, y *Fr) *Fr {
+	if debugChecks {
+		assertValid("Add", x, y)
+	}
 	var carry uint64
 	z[0], carry = bits.Add64(x[0], y[0], 0)
 	z[1], carry = bits.Add64(x[1], y[1], carry)
@@ -186,6 +405,9 @@ func (z *Fr) Add(x, y *Fr) *Fr {
 
 // Sub performs field subtraction: (a - b) mod r
 func (z *Fr) Sub(x, y *Fr) *Fr {
+	if debugChecks {
+		assertValid("Sub", x, y)
+	}
 	borrow := z.sub(x, y)
 	
 	// If there was a borrow, add r to get positive result
@@ -196,18 +418,208 @@ func (z *Fr) Sub(x, y *Fr) *Fr {
 }
 
 // Neg performs field negation: (-a) mod r
+// The input is reduced first and the difference reduced after, so any x < 2r
+// (including the zero case r - 0 = r) yields a canonical result without branching
 func (z *Fr) Neg(x *Fr) *Fr {
-	if x.IsZero() {
-		*z = Zero()
-		return z
-	}
-	z.sub(&rModulus, x)
+	t := *x
+	t.reduce()
+	z.sub(&rModulus, &t)
+	z.reduce()
 	return z
 }
 
 // Square performs field squaring: (a^2) mod r
+// The 512-bit square is formed with each cross product x[i]*x[j] (i < j) computed
+// once and doubled, 10 word multiplications instead of the 16 of Mul, and then
+// Montgomery reduced word by word (SOS). Everything is unrolled into scalar words;
+// compare BenchmarkSquare with BenchmarkMulSelf. For every canonical x the result
+// is identical to Mul(x, x)
 func (z *Fr) Square(x *Fr) *Fr {
-	return z.Mul(x, x)
+	if debugChecks {
+		assertValid("Square", x)
+	}
+	x0, x1, x2, x3 := x[0], x[1], x[2], x[3]
+	var t0, t1, t2, t3, t4, t5, t6, t7, t8, c, hi uint64
+	
+	// Cross products x[i]*x[j] for i < j
+	hi, t1 = bits.Mul64(x0, x1)
+	hi, t2 = madd(x0, x2, 0, hi)
+	t4, t3 = madd(x0, x3, 0, hi)
+	hi, t3 = madd(x1, x2, t3, 0)
+	t5, t4 = madd(x1, x3, t4, hi)
+	t6, t5 = madd(x2, x3, t5, 0)
+	
+	// Double them; the cross sum is at most x^2/2, so no bit is shifted out
+	t7 = t6 >> 63
+	t6 = t6<<1 | t5>>63
+	t5 = t5<<1 | t4>>63
+	t4 = t4<<1 | t3>>63
+	t3 = t3<<1 | t2>>63
+	t2 = t2<<1 | t1>>63
+	t1 <<= 1
+	
+	// Add the squares x[i]^2 on the diagonal
+	hi, t0 = bits.Mul64(x0, x0)
+	t1, c = bits.Add64(t1, hi, 0)
+	hi, lo := bits.Mul64(x1, x1)
+	t2, c = bits.Add64(t2, lo, c)
+	t3, c = bits.Add64(t3, hi, c)
+	hi, lo = bits.Mul64(x2, x2)
+	t4, c = bits.Add64(t4, lo, c)
+	t5, c = bits.Add64(t5, hi, c)
+	hi, lo = bits.Mul64(x3, x3)
+	t6, c = bits.Add64(t6, lo, c)
+	t7, _ = bits.Add64(t7, hi, c)
+	
+	// Montgomery reduction: clear one low word per step by adding m*r
+	m := t0 * nPrime
+	c, _ = madd(m, rModulus[0], t0, 0)
+	c, t1 = madd(m, rModulus[1], t1, c)
+	c, t2 = madd(m, rModulus[2], t2, c)
+	c, t3 = madd(m, rModulus[3], t3, c)
+	t4, c = bits.Add64(t4, c, 0)
+	t5, c = bits.Add64(t5, 0, c)
+	t6, c = bits.Add64(t6, 0, c)
+	t7, c = bits.Add64(t7, 0, c)
+	t8 = c
+	
+	m = t1 * nPrime
+	c, _ = madd(m, rModulus[0], t1, 0)
+	c, t2 = madd(m, rModulus[1], t2, c)
+	c, t3 = madd(m, rModulus[2], t3, c)
+	c, t4 = madd(m, rModulus[3], t4, c)
+	t5, c = bits.Add64(t5, c, 0)
+	t6, c = bits.Add64(t6, 0, c)
+	t7, c = bits.Add64(t7, 0, c)
+	t8 += c
+	
+	m = t2 * nPrime
+	c, _ = madd(m, rModulus[0], t2, 0)
+	c, t3 = madd(m, rModulus[1], t3, c)
+	c, t4 = madd(m, rModulus[2], t4, c)
+	c, t5 = madd(m, rModulus[3], t5, c)
+	t6, c = bits.Add64(t6, c, 0)
+	t7, c = bits.Add64(t7, 0, c)
+	t8 += c
+	
+	m = t3 * nPrime
+	c, _ = madd(m, rModulus[0], t3, 0)
+	c, t4 = madd(m, rModulus[1], t4, c)
+	c, t5 = madd(m, rModulus[2], t5, c)
+	c, t6 = madd(m, rModulus[3], t6, c)
+	t7, c = bits.Add64(t7, c, 0)
+	t8 += c
+	
+	z[0], z[1], z[2], z[3] = t4, t5, t6, t7
+	
+	// Final constant-time conditional subtraction, as in Add
+	var diff Fr
+	borrow := diff.sub(z, &rModulus)
+	z.cmov(z, &diff, t8|(1-borrow))
+	return z
+}
+
+// madd returns the two words of a*b + t + c, which cannot overflow 128 bits
+func madd(a, b, t, c uint64) (hi, lo uint64) {
+	var carry uint64
+	hi, lo = bits.Mul64(a, b)
+	lo, carry = bits.Add64(lo, t, 0)
+	hi += carry
+	lo, carry = bits.Add64(lo, c, 0)
+	hi += carry
+	return hi, lo
+}
+
+// Cube performs field cubing: (a^3) mod r
+func (z *Fr) Cube(x *Fr) *Fr {
+	var x2 Fr
+	x2.Square(x)
+	return z.Mul(&x2, x)
+}
+
+// Double performs field doubling: (2a) mod r
+func (z *Fr) Double(x *Fr) *Fr {
+	return z.Add(x, x)
+}
+
+// Halve performs field halving: (a/2) mod r, for canonical x
+// Halving is linear, so applying it to the Montgomery representation x*R gives
+// (x/2)*R directly: an even representation is shifted right, an odd one has r
+// (which is odd) added first. The parity selection is constant-time
+func (z *Fr) Halve(x *Fr) *Fr {
+	var sum Fr
+	carry := sum.add(x, &rModulus)
+	
+	odd := x[0] & 1
+	var t Fr
+	t.cmov(x, &sum, odd)
+	carry &= odd
+	
+	z[0] = t[0]>>1 | t[1]<<63
+	z[1] = t[1]>>1 | t[2]<<63
+	z[2] = t[2]>>1 | t[3]<<63
+	z[3] = t[3]>>1 | carry<<63
+	return z
+}
+
+// Exp performs field exponentiation: (x^e) mod r for a non-negative exponent
+// Uses left-to-right square-and-multiply; the running time depends on e but not on x
+func (z *Fr) Exp(x *Fr, e *big.Int) *Fr {
+	base := *x
+	result := One()
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		result.Square(&result)
+		if e.Bit(i) == 1 {
+			result.Mul(&result, &base)
+		}
+	}
+	*z = result
+	return z
+}
+
+// Inverse sets z = 1/x via Fermat's little theorem (x^(r-2)); the inverse of zero is zero
+func (z *Fr) Inverse(x *Fr) *Fr {
+	exponent := new(big.Int).Sub(limbsToBigInt(&rModulus), big.NewInt(2))
+	return z.Exp(x, exponent)
+}
+
+// Lsh sets z to (x << n) mod r, shifting the canonical integer value of x
+// Shifting then reducing equals multiplying by 2^n in the field, which works
+// directly on the Montgomery limbs without leaving Montgomery form
+func (z *Fr) Lsh(x *Fr, n uint) *Fr {
+	var pow Fr
+	if n < 64 {
+		pow = FromUint64(uint64(1) << n)
+	} else {
+		two := FromUint64(2)
+		pow.Exp(&two, new(big.Int).SetUint64(uint64(n)))
+	}
+	return z.Mul(x, &pow)
+}
+
+// Rsh sets z to x >> n, shifting the canonical integer value of x
+// This is integer floor division by 2^n, not field division; the result is
+// smaller than x and therefore already reduced
+func (z *Fr) Rsh(x *Fr, n uint) *Fr {
+	one := Fr{1, 0, 0, 0}
+	var v Fr
+	v.MulCIOS(x, &one) // Leave Montgomery form
+	
+	if n >= 256 {
+		v = Fr{}
+	} else {
+		words, bitShift := n/64, n%64
+		var shifted Fr
+		for i := 0; i+int(words) < 4; i++ {
+			shifted[i] = v[i+int(words)] >> bitShift
+			if bitShift != 0 && i+int(words)+1 < 4 {
+				shifted[i] |= v[i+int(words)+1] << (64 - bitShift)
+			}
+		}
+		v = shifted
+	}
+	
+	return z.Mul(&v, &montgomeryR2) // Back to Montgomery form
 }
 
 // Equal checks if two field elements are equal
@@ -215,11 +627,91 @@ func (f *Fr) Equal(other *Fr) bool {
 	return f[0] == other[0] && f[1] == other[1] && f[2] == other[2] && f[3] == other[3]
 }
 
+// EqualCT checks if two field elements are equal in constant time
+// All limb differences are ORed together with no early return, so the timing does
+// not reveal which limb differs; use it when comparing secret values such as tags
+func (f *Fr) EqualCT(other *Fr) bool {
+	diff := (f[0] ^ other[0]) | (f[1] ^ other[1]) | (f[2] ^ other[2]) | (f[3] ^ other[3])
+	return (diff|-diff)>>63 == 0
+}
+
+// Cmp compares the canonical integer values of f and other, returning -1, 0 or +1
+// Both are taken out of Montgomery form first, so the order is that of the
+// represented values in [0, r), not of the stored limbs
+func (f *Fr) Cmp(other *Fr) int {
+	one := Fr{1, 0, 0, 0}
+	var a, b Fr
+	a.MulCIOS(f, &one)
+	b.MulCIOS(other, &one)
+	for i := 3; i >= 0; i-- {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// EqualSlices reports whether a and b hold the same field elements in the same order
+// Elements are compared by canonical value, so a non-canonical limb representation
+// matches its reduced form
+func EqualSlices(a, b []Fr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(&b[i]) && a[i].ToBytes32() != b[i].ToBytes32() {
+			return false
+		}
+	}
+	return true
+}
+
+// FrKey returns a canonical string for f, suitable as a map key
+// The key is the 32-byte big-endian canonical encoding, so every limb
+// representation of the same value yields the same key
+func FrKey(f Fr) string {
+	b := f.ToBytes32()
+	return string(b[:])
+}
+
+// IsValid reports whether f is in canonical form (its limbs encode a value < r)
+func (f *Fr) IsValid() bool {
+	var t Fr
+	return t.sub(f, &rModulus) == 1
+}
+
+// Canonicalize returns f with its limbs reduced to the canonical range [0, r)
+// The limbs are treated as a plain 256-bit integer, so the result is congruent mod r
+// in either domain: regular-form limbs stay regular and Montgomery-form limbs stay
+// Montgomery. Since 2^256 < 6r, five constant-time conditional subtractions suffice
+func Canonicalize(f Fr) Fr {
+	for i := 0; i < 5; i++ {
+		f.reduce()
+	}
+	return f
+}
+
 // IsZero checks if the field element is zero
 func (f *Fr) IsZero() bool {
 	return f[0] == 0 && f[1] == 0 && f[2] == 0 && f[3] == 0
 }
 
+// IsOne checks if the field element is the multiplicative identity
+// One is montgomeryR in Montgomery form, so this is a plain limb comparison
+func (f *Fr) IsOne() bool {
+	return *f == montgomeryR
+}
+
+// IsMinusOne checks if the field element is r - 1, i.e. -One()
+func (f *Fr) IsMinusOne() bool {
+	var minusOne Fr
+	minusOne.sub(&rModulus, &montgomeryR) // -R mod r, the Montgomery form of r - 1
+	return *f == minusOne
+}
+
 // Set copies another field element
 func (z *Fr) Set(x *Fr) *Fr {
 	z[0], z[1], z[2], z[3] = x[0], x[1], x[2], x[3]
@@ -257,6 +749,22 @@ func (z *Fr) cmov(y, x *Fr, cond uint64) {
 	z[3] = y[3] ^ ((y[3] ^ x[3]) & mask)
 }
 
+// Select sets z = x0 if c == 0 and z = x1 otherwise, in constant time
+func (z *Fr) Select(c int, x0, x1 *Fr) *Fr {
+	cond := uint64(c)
+	cond = (cond | -cond) >> 63 // 1 for any nonzero c
+	z.cmov(x0, x1, cond)
+	return z
+}
+
+// cswap swaps a and b when bit == 1 and leaves them when bit == 0 (constant-time)
+// Both outputs are chosen with Select, so neither depends on bit through a branch
+func cswap(a, b *Fr, bit uint64) {
+	x0, x1 := *a, *b
+	a.Select(int(bit), &x0, &x1)
+	b.Select(int(bit), &x1, &x0)
+}
+
 // reduce ensures the result is < r (for internal use)
 func (z *Fr) reduce() {
 	var temp Fr
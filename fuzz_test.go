@@ -0,0 +1,98 @@
+package poseidon2
+
+import (
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+// fuzzSeeds32 returns the 32-byte edge cases used to seed the field fuzzers
+func fuzzSeeds32() [][]byte {
+	modulus := limbsToBigInt(&rModulus)
+	
+	r := make([]byte, 32)
+	modulus.FillBytes(r)
+	rMinusOne := make([]byte, 32)
+	new(big.Int).Sub(modulus, big.NewInt(1)).FillBytes(rMinusOne)
+	rPlusOne := make([]byte, 32)
+	new(big.Int).Add(modulus, big.NewInt(1)).FillBytes(rPlusOne)
+	allOnes := make([]byte, 32)
+	for i := range allOnes {
+		allOnes[i] = 0xFF
+	}
+	one := make([]byte, 32)
+	one[31] = 1
+	
+	return [][]byte{make([]byte, 32), one, rMinusOne, r, rPlusOne, allOnes}
+}
+
+// toArray32 copies up to 32 bytes of data into a right-aligned array
+func toArray32(data []byte) [32]byte {
+	var arr [32]byte
+	if len(data) > 32 {
+		data = data[len(data)-32:]
+	}
+	copy(arr[32-len(data):], data)
+	return arr
+}
+
+// FuzzFieldRoundTrip checks FromBytes/ToBytes32 against big.Int reduction mod r
+// Run with: go test -fuzz=FuzzFieldRoundTrip
+func FuzzFieldRoundTrip(f *testing.F) {
+	for _, seed := range fuzzSeeds32() {
+		f.Add(seed)
+	}
+	modulus := limbsToBigInt(&rModulus)
+	
+	f.Fuzz(func(t *testing.T, data []byte) {
+		in := toArray32(data)
+		out := FromBytes(in).ToBytes32()
+		
+		want := new(big.Int).SetBytes(in[:])
+		want.Mod(want, modulus)
+		var wantBytes [32]byte
+		want.FillBytes(wantBytes[:])
+		
+		if out != wantBytes {
+			t.Fatalf("FromBytes(%x).ToBytes32() = %x, want %x", in, out, wantBytes)
+		}
+	})
+}
+
+// FuzzMul differentially checks Mul against big.Int multiplication mod r
+// Mul is the CIOS Montgomery multiplication, so this covers the hot path directly
+// Run with: go test -fuzz=FuzzMul
+func FuzzMul(f *testing.F) {
+	seeds := fuzzSeeds32()
+	for _, a := range seeds {
+		for _, b := range seeds {
+			f.Add(a, b)
+		}
+	}
+	rng := rand.New(rand.NewSource(42))
+	for i := 0; i < 4; i++ {
+		a := randomFr(rng).ToBytes32()
+		b := randomFr(rng).ToBytes32()
+		f.Add(a[:], b[:])
+	}
+	modulus := limbsToBigInt(&rModulus)
+	
+	f.Fuzz(func(t *testing.T, aData, bData []byte) {
+		aIn := toArray32(aData)
+		bIn := toArray32(bData)
+		a := FromBytes(aIn)
+		b := FromBytes(bIn)
+		
+		var got Fr
+		got.Mul(&a, &b)
+		if !isCanonical(&got) {
+			t.Fatalf("Mul(%x, %x) left a non-canonical result", aIn, bIn)
+		}
+		
+		want := new(big.Int).Mul(new(big.Int).SetBytes(aIn[:]), new(big.Int).SetBytes(bIn[:]))
+		want.Mod(want, modulus)
+		if got.ToBigInt().Cmp(want) != 0 {
+			t.Fatalf("Mul(%x, %x) = %s, want %s", aIn, bIn, got.ToBigInt(), want)
+		}
+	})
+}
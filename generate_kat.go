@@ -1,6 +1,7 @@
 package poseidon2
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 )
@@ -35,6 +36,13 @@ type BytesHashTestVector struct {
 	Expected    string `json:"expected"`
 }
 
+// MerkleTestVector represents a Merkle root test case
+type MerkleTestVector struct {
+	Description string   `json:"description"`
+	Leaves      []string `json:"leaves"`
+	Root        string   `json:"root"`
+}
+
 // GeneratedKAT represents the complete set of generated KAT vectors
 type GeneratedKAT struct {
 	FieldModulus     string                `json:"field_modulus"`
@@ -43,6 +51,8 @@ type GeneratedKAT struct {
 	HashTests        []HashTestVector      `json:"hash_tests"`
 	Compress2Tests   []Compress2TestVector `json:"compress2_tests"`
 	BytesHashTests   []BytesHashTestVector `json:"bytes_hash_tests"`
+	LengthTagTests   []HashTestVector      `json:"length_tag_hash_tests,omitempty"`
+	MerkleTests      []MerkleTestVector    `json:"merkle_tests,omitempty"`
 }
 
 // GenerateKATVectors generates Known Answer Test vectors using the actual implementation
@@ -87,6 +97,20 @@ func GenerateKATVectors() (*GeneratedKAT, error) {
 	}
 	kat.BytesHashTests = bytesHashTests
 	
+	// Generate length-tagged hash test vectors
+	lengthTagTests, err := generateLengthTagVectors()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate length-tag vectors: %w", err)
+	}
+	kat.LengthTagTests = lengthTagTests
+	
+	// Generate Merkle root test vectors
+	merkleTests, err := generateMerkleVectors()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate merkle vectors: %w", err)
+	}
+	kat.MerkleTests = merkleTests
+	
 	return kat, nil
 }
 
@@ -261,6 +285,148 @@ func generateBytesHashVectors() ([]BytesHashTestVector, error) {
 	return vectors, nil
 }
 
+func generateLengthTagVectors() ([]HashTestVector, error) {
+	var vectors []HashTestVector
+	
+	inputs := []struct {
+		description string
+		hex         []string
+	}{
+		{"Length-tagged hash of empty input", []string{}},
+		{"Length-tagged hash [1]", []string{"0x1"}},
+		{"Length-tagged hash [1, 0]", []string{"0x1", "0x0"}},
+		{"Length-tagged hash [1, 2, 3]", []string{"0x1", "0x2", "0x3"}},
+	}
+	
+	for _, in := range inputs {
+		elements, err := hexSliceToFrSlice(in.hex)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, HashTestVector{
+			Description: in.description,
+			Input:       in.hex,
+			Expected:    frToHex(HashWithLengthTag(elements...)),
+		})
+	}
+	
+	return vectors, nil
+}
+
+func generateMerkleVectors() ([]MerkleTestVector, error) {
+	var vectors []MerkleTestVector
+	
+	// Leaves are 1..n; odd counts exercise the zero padding
+	for _, n := range []int{1, 2, 3, 4, 8} {
+		leaves := make([]Fr, n)
+		for i := range leaves {
+			leaves[i] = FromUint64(uint64(i + 1))
+		}
+		tree, err := BuildMerkleTree(leaves)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, MerkleTestVector{
+			Description: fmt.Sprintf("Merkle root of %d leaves [1..%d]", n, n),
+			Leaves:      frSliceToHexSlice(leaves),
+			Root:        frToHex(tree.Root()),
+		})
+	}
+	
+	return vectors, nil
+}
+
+// katRand is a SplitMix64 generator used for reproducible KAT inputs
+// SplitMix64 is trivial to port, so other implementations can regenerate the same inputs
+type katRand struct {
+	state uint64
+}
+
+// next returns the next 64-bit output of the generator
+func (r *katRand) next() uint64 {
+	r.state += 0x9e3779b97f4a7c15
+	z := r.state
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// fr returns a field element built from 256 random bits reduced mod r
+func (r *katRand) fr() Fr {
+	var data [32]byte
+	for i := 0; i < 32; i += 8 {
+		binary.BigEndian.PutUint64(data[i:], r.next())
+	}
+	return FromBytes(data)
+}
+
+// GenerateRandomKAT generates count vectors of each kind from inputs drawn from a
+// SplitMix64 generator seeded with seed, so the same seed always yields the same vectors
+func GenerateRandomKAT(seed uint64, count int) *GeneratedKAT {
+	rng := &katRand{state: seed}
+	kat := &GeneratedKAT{
+		FieldModulus: "0x" + limbsToBigInt(&rModulus).Text(16),
+		Parameters: map[string]int{
+			"t":        T,
+			"d":        5, // S-box degree
+			"F":        FULL_ROUNDS,
+			"P":        PARTIAL_ROUNDS,
+			"rate":     2,
+			"capacity": 1,
+		},
+	}
+	
+	for i := 0; i < count; i++ {
+		// Permutation over a random state
+		var state [T]Fr
+		for j := range state {
+			state[j] = rng.fr()
+		}
+		input := frSliceToHexSlice(state[:])
+		ProductionPermutation(&state)
+		kat.PermutationTests = append(kat.PermutationTests, TestVector{
+			Description: fmt.Sprintf("Random permutation %d (seed %d)", i, seed),
+			Input:       input,
+			Expected:    frSliceToHexSlice(state[:]),
+		})
+		
+		// Hash over 1..8 random elements
+		elements := make([]Fr, 1+rng.next()%8)
+		for j := range elements {
+			elements[j] = rng.fr()
+		}
+		kat.HashTests = append(kat.HashTests, HashTestVector{
+			Description: fmt.Sprintf("Random hash %d (seed %d)", i, seed),
+			Input:       frSliceToHexSlice(elements),
+			Expected:    frToHex(Hash(elements...)),
+		})
+		
+		// Compress2 over two random elements
+		a, b := rng.fr(), rng.fr()
+		kat.Compress2Tests = append(kat.Compress2Tests, Compress2TestVector{
+			Description: fmt.Sprintf("Random compress2 %d (seed %d)", i, seed),
+			A:           frToHex(a),
+			B:           frToHex(b),
+			Expected:    frToHex(Compress2(a, b)),
+		})
+		
+		// HashBytes over 1..100 random bytes
+		data := make([]byte, 1+rng.next()%100)
+		for j := range data {
+			data[j] = byte(rng.next())
+		}
+		digest, _ := HashBytes(DomainGeneric, data) // HashBytes never fails for in-memory data
+		kat.BytesHashTests = append(kat.BytesHashTests, BytesHashTestVector{
+			Description: fmt.Sprintf("Random bytes hash %d (seed %d)", i, seed),
+			Domain:      fmt.Sprintf("0x%08x", uint32(DomainGeneric)),
+			Data:        fmt.Sprintf("%x", data),
+			Expected:    fmt.Sprintf("0x%x", digest),
+		})
+	}
+	
+	return kat
+}
+
 // GenerateKATJSON generates KAT vectors and returns them as JSON string
 func GenerateKATJSON() (string, error) {
 	kat, err := GenerateKATVectors()
@@ -0,0 +1,17 @@
+package poseidon2
+
+import "sync/atomic"
+
+// permutationCalls counts ProductionPermutation calls when instrumentCalls is set
+var permutationCalls atomic.Uint64
+
+// PermutationCallCount returns the number of ProductionPermutation calls since the last reset
+// Counting only happens in builds with the poseidon2_instrument tag; otherwise it is always 0
+func PermutationCallCount() uint64 {
+	return permutationCalls.Load()
+}
+
+// ResetPermutationCallCount sets the permutation call counter back to zero
+func ResetPermutationCallCount() {
+	permutationCalls.Store(0)
+}
@@ -0,0 +1,7 @@
+//go:build !poseidon2_instrument
+
+package poseidon2
+
+// instrumentCalls enables the permutation call counter
+// Build with -tags poseidon2_instrument to turn it on
+const instrumentCalls = false
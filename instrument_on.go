@@ -0,0 +1,6 @@
+//go:build poseidon2_instrument
+
+package poseidon2
+
+// instrumentCalls enables the permutation call counter
+const instrumentCalls = true
@@ -0,0 +1,34 @@
+//go:build poseidon2_instrument
+
+package poseidon2
+
+import "testing"
+
+// TestPermutationCallCount checks the instrumented counter for common operations
+func TestPermutationCallCount(t *testing.T) {
+	elements := []Fr{FromUint64(1), FromUint64(2), FromUint64(3), FromUint64(4)}
+	
+	cases := []struct {
+		name string
+		run  func()
+		want uint64
+	}{
+		{"Compress2", func() { Compress2(elements[0], elements[1]) }, 1},
+		{"HashTwoFast", func() { HashTwoFast(elements[0], elements[1]) }, 1},
+		{"Hash of 4 elements", func() { Hash(elements...) }, 2},
+		{"Hash of 3 elements", func() { Hash(elements[:3]...) }, 2},
+		{"empty Hash", func() { Hash() }, 1},
+	}
+	for _, tc := range cases {
+		ResetPermutationCallCount()
+		tc.run()
+		if got := PermutationCallCount(); got != tc.want {
+			t.Errorf("%s triggered %d permutations, want %d", tc.name, got, tc.want)
+		}
+	}
+	
+	ResetPermutationCallCount()
+	if PermutationCallCount() != 0 {
+		t.Error("ResetPermutationCallCount did not clear the counter")
+	}
+}
@@ -0,0 +1,166 @@
+package poseidon2
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// KATMismatch describes a vector whose recomputed output differs from the file
+type KATMismatch struct {
+	Kind        string // "permutation", "hash", "length_tag_hash", "compress2", "bytes_hash" or "merkle"
+	Description string
+	Expected    string
+	Got         string
+}
+
+// CompareAgainstKATFile recomputes every vector in a KAT file with this implementation
+// Both the wrapped kat/kat.json layout ({"poseidon2_test_vectors": {...}}) and the
+// flat GenerateKATJSON layout are accepted. Differences are returned as mismatches;
+// an error is returned only when the file cannot be read or a vector cannot be parsed
+func CompareAgainstKATFile(path string) ([]KATMismatch, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KAT file: %w", err)
+	}
+	
+	var wrapped struct {
+		Vectors *GeneratedKAT `json:"poseidon2_test_vectors"`
+	}
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return nil, fmt.Errorf("failed to parse KAT file: %w", err)
+	}
+	kat := wrapped.Vectors
+	if kat == nil {
+		kat = &GeneratedKAT{}
+		if err := json.Unmarshal(data, kat); err != nil {
+			return nil, fmt.Errorf("failed to parse KAT file: %w", err)
+		}
+	}
+	
+	return compareKAT(kat)
+}
+
+// compareKAT recomputes the vectors of kat and collects mismatches
+func compareKAT(kat *GeneratedKAT) ([]KATMismatch, error) {
+	var mismatches []KATMismatch
+	
+	for _, tv := range kat.PermutationTests {
+		input, err := hexSliceToFrSlice(tv.Input)
+		if err != nil {
+			return nil, fmt.Errorf("permutation vector %q: %w", tv.Description, err)
+		}
+		if len(input) != T {
+			return nil, fmt.Errorf("permutation vector %q: input has %d elements, want %d", tv.Description, len(input), T)
+		}
+		expected, err := hexSliceToFrSlice(tv.Expected)
+		if err != nil {
+			return nil, fmt.Errorf("permutation vector %q: %w", tv.Description, err)
+		}
+		
+		var state [T]Fr
+		copy(state[:], input)
+		ProductionPermutation(&state)
+		if !EqualSlices(state[:], expected) {
+			mismatches = append(mismatches, KATMismatch{
+				Kind:        "permutation",
+				Description: tv.Description,
+				Expected:    strings.Join(frSliceToHexSlice(expected), ","),
+				Got:         strings.Join(frSliceToHexSlice(state[:]), ","),
+			})
+		}
+	}
+	
+	for _, tv := range kat.HashTests {
+		input, err := hexSliceToFrSlice(tv.Input)
+		if err != nil {
+			return nil, fmt.Errorf("hash vector %q: %w", tv.Description, err)
+		}
+		expected, err := hexToFr(tv.Expected)
+		if err != nil {
+			return nil, fmt.Errorf("hash vector %q: %w", tv.Description, err)
+		}
+		
+		if got := Hash(input...); !got.Equal(&expected) {
+			mismatches = append(mismatches, KATMismatch{Kind: "hash", Description: tv.Description, Expected: frToHex(expected), Got: frToHex(got)})
+		}
+	}
+	
+	for _, tv := range kat.LengthTagTests {
+		input, err := hexSliceToFrSlice(tv.Input)
+		if err != nil {
+			return nil, fmt.Errorf("length-tag vector %q: %w", tv.Description, err)
+		}
+		expected, err := hexToFr(tv.Expected)
+		if err != nil {
+			return nil, fmt.Errorf("length-tag vector %q: %w", tv.Description, err)
+		}
+		
+		if got := HashWithLengthTag(input...); !got.Equal(&expected) {
+			mismatches = append(mismatches, KATMismatch{Kind: "length_tag_hash", Description: tv.Description, Expected: frToHex(expected), Got: frToHex(got)})
+		}
+	}
+	
+	for _, tv := range kat.Compress2Tests {
+		a, err := hexToFr(tv.A)
+		if err != nil {
+			return nil, fmt.Errorf("compress2 vector %q: %w", tv.Description, err)
+		}
+		b, err := hexToFr(tv.B)
+		if err != nil {
+			return nil, fmt.Errorf("compress2 vector %q: %w", tv.Description, err)
+		}
+		expected, err := hexToFr(tv.Expected)
+		if err != nil {
+			return nil, fmt.Errorf("compress2 vector %q: %w", tv.Description, err)
+		}
+		
+		if got := Compress2(a, b); !got.Equal(&expected) {
+			mismatches = append(mismatches, KATMismatch{Kind: "compress2", Description: tv.Description, Expected: frToHex(expected), Got: frToHex(got)})
+		}
+	}
+	
+	for _, tv := range kat.BytesHashTests {
+		domain, err := strconv.ParseUint(strings.TrimPrefix(tv.Domain, "0x"), 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bytes hash vector %q: invalid domain: %w", tv.Description, err)
+		}
+		data, err := hex.DecodeString(tv.Data)
+		if err != nil {
+			return nil, fmt.Errorf("bytes hash vector %q: invalid data: %w", tv.Description, err)
+		}
+		
+		got, err := HashBytes(Domain(domain), data)
+		if err != nil {
+			return nil, fmt.Errorf("bytes hash vector %q: %w", tv.Description, err)
+		}
+		gotHex := fmt.Sprintf("0x%x", got)
+		if !strings.EqualFold(gotHex, tv.Expected) {
+			mismatches = append(mismatches, KATMismatch{Kind: "bytes_hash", Description: tv.Description, Expected: tv.Expected, Got: gotHex})
+		}
+	}
+	
+	for _, tv := range kat.MerkleTests {
+		leaves, err := hexSliceToFrSlice(tv.Leaves)
+		if err != nil {
+			return nil, fmt.Errorf("merkle vector %q: %w", tv.Description, err)
+		}
+		expected, err := hexToFr(tv.Root)
+		if err != nil {
+			return nil, fmt.Errorf("merkle vector %q: %w", tv.Description, err)
+		}
+		tree, err := BuildMerkleTree(leaves)
+		if err != nil {
+			return nil, fmt.Errorf("merkle vector %q: %w", tv.Description, err)
+		}
+		
+		if got := tree.Root(); !got.Equal(&expected) {
+			mismatches = append(mismatches, KATMismatch{Kind: "merkle", Description: tv.Description, Expected: frToHex(expected), Got: frToHex(got)})
+		}
+	}
+	
+	return mismatches, nil
+}
@@ -32,6 +32,39 @@ func hexToFr(hexStr string) (Fr, error) {
 	return FromBytes(arr), nil
 }
 
+// hexToFrLE converts a little-endian hex string (first byte least significant) to a Fr element
+// An odd-length string gets a leading zero nibble so its first byte is read whole
+// ("0x1" is 1, not 16); short strings are then padded with zero bytes on the right,
+// which is the high end in this byte order
+func hexToFrLE(hexStr string) (Fr, error) {
+	if strings.HasPrefix(hexStr, "0x") || strings.HasPrefix(hexStr, "0X") {
+		hexStr = hexStr[2:]
+	}
+	if len(hexStr)%2 == 1 {
+		hexStr = "0" + hexStr
+	}
+	if len(hexStr) < 64 {
+		hexStr = hexStr + strings.Repeat("0", 64-len(hexStr))
+	}
+	
+	bytes, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return Fr{}, fmt.Errorf("failed to decode hex string '%s': %w", hexStr, err)
+	}
+	
+	var arr [32]byte
+	copy(arr[:], bytes)
+	return FromBytesLE(arr), nil
+}
+
+// hexToFrOrder converts a hex string in the given byte order to a Fr element
+func hexToFrOrder(hexStr string, littleEndian bool) (Fr, error) {
+	if littleEndian {
+		return hexToFrLE(hexStr)
+	}
+	return hexToFr(hexStr)
+}
+
 // hexSliceToFrSlice converts a slice of hex strings to a slice of Fr elements
 func hexSliceToFrSlice(hexSlice []string) ([]Fr, error) {
 	frSlice := make([]Fr, len(hexSlice))
@@ -47,8 +80,13 @@ func hexSliceToFrSlice(hexSlice []string) ([]Fr, error) {
 
 // frToHex converts a Fr element to a hex string (with 0x prefix)
 func frToHex(fr Fr) string {
-	bytes := fr.ToBytes32()
-	return "0x" + hex.EncodeToString(bytes[:])
+	return fr.Hex()
+}
+
+// frToHexLE converts a Fr element to a little-endian hex string (with 0x prefix)
+func frToHexLE(fr Fr) string {
+	b := fr.ToBytesLE()
+	return "0x" + hex.EncodeToString(b[:])
 }
 
 // frSliceToHexSlice converts a slice of Fr elements to hex strings
@@ -0,0 +1,12 @@
+package poseidon2
+
+// domainLabel separates label-derived elements from every other byte hash
+const domainLabel Domain = 0x5347424c // "SGBL"
+
+// LabelToFr maps a human-readable label to a field element
+// The result is HashBytes(domainLabel, []byte(label)) read as a field element, so
+// it is stable across runs and builds and distinct labels collide only if the hash does
+func LabelToFr(label string) Fr {
+	digest := HashBytesCT(domainLabel, []byte(label)) // Same digest as HashBytes, without the error path
+	return FromBytes(digest)
+}
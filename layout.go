@@ -0,0 +1,44 @@
+package poseidon2
+
+import "fmt"
+
+// State layout helpers for batched processing
+//
+// A slice of states ([][T]Fr) is an array-of-structures layout: the T elements of
+// one state are adjacent. Vectorized or GPU permutations want the transpose, a
+// structure-of-arrays where element i of every state is contiguous, so the same
+// round operation can be applied to a whole column at once. TransposeStates and
+// UntransposeStates convert between the two without changing any values.
+
+// TransposeStates converts states into T columns, column i holding element i of every state
+func TransposeStates(states [][T]Fr) [T][]Fr {
+	var columns [T][]Fr
+	for i := range columns {
+		columns[i] = make([]Fr, len(states))
+	}
+	for j, state := range states {
+		for i := 0; i < T; i++ {
+			columns[i][j] = state[i]
+		}
+	}
+	return columns
+}
+
+// UntransposeStates is the inverse of TransposeStates
+// All columns must have the same length, which becomes the number of states
+func UntransposeStates(columns [T][]Fr) ([][T]Fr, error) {
+	n := len(columns[0])
+	for i := 1; i < T; i++ {
+		if len(columns[i]) != n {
+			return nil, fmt.Errorf("column %d has %d elements, expected %d", i, len(columns[i]), n)
+		}
+	}
+	
+	states := make([][T]Fr, n)
+	for j := range states {
+		for i := 0; i < T; i++ {
+			states[j][i] = columns[i][j]
+		}
+	}
+	return states, nil
+}
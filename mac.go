@@ -0,0 +1,21 @@
+package poseidon2
+
+// MAC computes a keyed Poseidon2 tag over message
+// The key seeds the capacity element, so it never sits in the rate portion that is
+// absorbed into or squeezed from. Security assumes a uniform secret key and that the
+// permutation behaves like a random one (sponge indifferentiability), which Poseidon2
+// does not prove. As with Hash there is no padding: messages differing only by
+// trailing Zero() elements share a tag, so authenticate fixed-length messages
+func MAC(key Fr, message ...Fr) Fr {
+	hasher := NewHasherWithIV(key)
+	hasher.AbsorbMany(message)
+	return hasher.Finalize()
+}
+
+// VerifyMAC reports whether tag is MAC(key, message...)
+// The recomputed tag is compared with EqualCT, so a forged tag is rejected in the same
+// time wherever it first differs. Tags must be in the canonical form MAC returns
+func VerifyMAC(key Fr, tag Fr, message ...Fr) bool {
+	expected := MAC(key, message...)
+	return expected.EqualCT(&tag)
+}
@@ -0,0 +1,167 @@
+package poseidon2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// checkSquare returns an error unless m is a non-empty square matrix
+func checkSquare(m [][]Fr) error {
+	if len(m) == 0 {
+		return errors.New("matrix is empty")
+	}
+	for i, row := range m {
+		if len(row) != len(m) {
+			return fmt.Errorf("matrix is not square: row %d has %d entries, want %d", i, len(row), len(m))
+		}
+	}
+	return nil
+}
+
+// Determinant returns the determinant of a square matrix over the field
+// An error is returned for an empty or non-square matrix; m is not modified
+func Determinant(m [][]Fr) (Fr, error) {
+	if err := checkSquare(m); err != nil {
+		return Fr{}, err
+	}
+	return determinant(m), nil
+}
+
+// MatrixInverse returns the inverse of a square matrix over the field
+// Gauss-Jordan elimination on [m | I] is used, so the cost is O(n^3) Mul plus n
+// field inversions. Singular, empty and non-square matrices are rejected; m is not modified
+func MatrixInverse(m [][]Fr) ([][]Fr, error) {
+	if err := checkSquare(m); err != nil {
+		return nil, err
+	}
+
+	n := len(m)
+	a := make([][]Fr, n)   // Reduced to the identity
+	inv := make([][]Fr, n) // Accumulates the inverse
+	for i := range m {
+		a[i] = append([]Fr(nil), m[i]...)
+		inv[i] = make([]Fr, n)
+		inv[i][i] = One()
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for row := col; row < n; row++ {
+			if !a[row][col].IsZero() {
+				pivot = row
+				break
+			}
+		}
+		if pivot < 0 {
+			return nil, errors.New("matrix is singular")
+		}
+		a[pivot], a[col] = a[col], a[pivot]
+		inv[pivot], inv[col] = inv[col], inv[pivot]
+
+		// Scale the pivot row so the pivot becomes one
+		var scale Fr
+		scale.Inverse(&a[col][col])
+		for k := 0; k < n; k++ {
+			a[col][k].Mul(&a[col][k], &scale)
+			inv[col][k].Mul(&inv[col][k], &scale)
+		}
+
+		// Clear the pivot column in every other row
+		for row := 0; row < n; row++ {
+			if row == col || a[row][col].IsZero() {
+				continue
+			}
+			factor := a[row][col]
+			for k := 0; k < n; k++ {
+				var term Fr
+				term.Mul(&factor, &a[col][k])
+				a[row][k].Sub(&a[row][k], &term)
+				term.Mul(&factor, &inv[col][k])
+				inv[row][k].Sub(&inv[row][k], &term)
+			}
+		}
+	}
+	return inv, nil
+}
+
+// determinant returns det(m) for a square matrix by Gaussian elimination
+// m is not modified and must be square (see Determinant). Each pivot is the first
+// nonzero entry of its column; the row swaps flip the sign, and a column without
+// a pivot means det(m) = 0
+func determinant(m [][]Fr) Fr {
+	n := len(m)
+	a := make([][]Fr, n)
+	for i := range m {
+		a[i] = append([]Fr(nil), m[i]...)
+	}
+
+	det := One()
+	for col := 0; col < n; col++ {
+		pivot := -1
+		for row := col; row < n; row++ {
+			if !a[row][col].IsZero() {
+				pivot = row
+				break
+			}
+		}
+		if pivot < 0 {
+			return Zero()
+		}
+		if pivot != col {
+			a[pivot], a[col] = a[col], a[pivot]
+			det.Neg(&det)
+		}
+
+		det.Mul(&det, &a[col][col])
+		var inv Fr
+		inv.Inverse(&a[col][col])
+		for row := col + 1; row < n; row++ {
+			var factor Fr
+			factor.Mul(&a[row][col], &inv)
+			for k := col; k < n; k++ {
+				var term Fr
+				term.Mul(&factor, &a[col][k])
+				a[row][k].Sub(&a[row][k], &term)
+			}
+		}
+	}
+	return det
+}
+
+// IsMDS reports whether matrix is maximum distance separable
+// A matrix is MDS exactly when every square submatrix (every k x k minor for
+// k = 1..T, choosing any k rows and any k columns) is nonsingular, which is
+// checked directly here: for t=3 that is the 9 entries, 9 2x2 minors and the determinant
+func IsMDS(matrix [T][T]Fr) bool {
+	for rows := 1; rows < 1<<T; rows++ {
+		for cols := 1; cols < 1<<T; cols++ {
+			rowIdx, colIdx := maskIndices(rows), maskIndices(cols)
+			if len(rowIdx) != len(colIdx) {
+				continue
+			}
+
+			sub := make([][]Fr, len(rowIdx))
+			for i, r := range rowIdx {
+				sub[i] = make([]Fr, len(colIdx))
+				for j, c := range colIdx {
+					sub[i][j] = matrix[r][c]
+				}
+			}
+			if det := determinant(sub); det.IsZero() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// maskIndices returns the positions of the set bits of mask in increasing order
+func maskIndices(mask int) []int {
+	var idx []int
+	for i := 0; mask>>i != 0; i++ {
+		if mask>>i&1 == 1 {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
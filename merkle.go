@@ -0,0 +1,190 @@
+package poseidon2
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MerkleTree is a binary Merkle tree over field elements
+// Internal nodes are MerkleNode(left, right); leaves are padded with Zero()
+// up to the next power of two so every tree is perfect
+type MerkleTree struct {
+	size   int    // Number of leaves supplied by the caller (unpadded)
+	levels [][]Fr // levels[0] holds the padded leaves, the last level holds the root
+}
+
+// BuildMerkleTree constructs a Merkle tree from the given leaves
+// There is no empty tree: zero leaves is an error rather than a sentinel root, so
+// an empty set can never be confused with a set holding a Zero() leaf. A single
+// leaf is a depth-0 tree whose root is the leaf itself, unhashed; callers needing
+// a digest of the leaf bytes should pass LeafHash output as leaves
+func BuildMerkleTree(leaves []Fr) (*MerkleTree, error) {
+	if len(leaves) == 0 {
+		return nil, errors.New("cannot build Merkle tree with no leaves")
+	}
+
+	width := 1 << treeDepth(len(leaves))
+	level := make([]Fr, width) // Padding positions stay Zero()
+	copy(level, leaves)
+
+	levels := [][]Fr{level}
+	for len(level) > 1 {
+		next := make([]Fr, len(level)/2)
+		for i := range next {
+			next[i] = MerkleNode(level[2*i], level[2*i+1])
+		}
+		levels = append(levels, next)
+		level = next
+	}
+
+	return &MerkleTree{size: len(leaves), levels: levels}, nil
+}
+
+// merkleNodeIV is the capacity element of every internal node compression
+var merkleNodeIV = FromUint64(uint64(DomainMerkleNode))
+
+// MerkleNode compresses two children into their parent node
+// It is Compress2 with DomainMerkleNode in the capacity instead of zero, a single
+// permutation of [left, right, DomainMerkleNode]. Leaves are seeded with
+// DomainMerkleLeaf instead, so a node can never be passed off as a leaf or vice
+// versa (the classic second-preimage attack on Merkle trees)
+func MerkleNode(left, right Fr) Fr {
+	state := [T]Fr{left, right, merkleNodeIV}
+	ProductionPermutation(&state)
+	return state[0]
+}
+
+// MerkleNodeBytes returns MerkleNode of two 32-byte children in canonical
+// 32-byte big-endian form. It is the byte-oriented node compression for trees
+// built by BuildMerkleTree; HashPair is not tree-compatible
+func MerkleNodeBytes(left, right [32]byte) [32]byte {
+	result := MerkleNode(FromBytes(left), FromBytes(right))
+	return result.ToBytes32()
+}
+
+// LeafHash maps raw leaf bytes to a field element suitable for BuildMerkleTree
+// The capacity is seeded with DomainMerkleLeaf, which internal nodes (MerkleNode)
+// never use, so a leaf digest can never be reinterpreted as a node and vice versa.
+// The application tag is absorbed next, then data as PackBytes(data), whose
+// trailing length keeps inputs that differ only by trailing zero bytes apart
+func LeafHash(tag Domain, data []byte) Fr {
+	hasher := NewHasherWithIV(FromUint64(uint64(DomainMerkleLeaf)))
+	hasher.Absorb(FromUint64(uint64(tag)))
+	absorbBytesCT(hasher, data)
+	return hasher.Finalize()
+}
+
+// domainRoots marks the capacity of root aggregation, keeping it apart from leaves and nodes
+const domainRoots Domain = 0x53475241 // "SGRA"
+
+// HashRoots aggregates already-computed digests, such as sub-tree roots, under tag
+// The capacity is seeded with a reserved root-aggregation constant (nodes and leaves
+// use DomainMerkleNode and DomainMerkleLeaf there), then tag, len(roots) and the roots are
+// absorbed. The count keeps trailing Zero() roots significant; the result depends on order
+func HashRoots(tag Domain, roots ...Fr) Fr {
+	hasher := NewHasherWithIV(FromUint64(uint64(domainRoots)))
+	hasher.Absorb(FromUint64(uint64(tag)))
+	hasher.Absorb(FromUint64(uint64(len(roots))))
+	hasher.AbsorbMany(roots)
+	return hasher.Finalize()
+}
+
+// Root returns the root of the tree
+func (t *MerkleTree) Root() Fr {
+	return t.levels[len(t.levels)-1][0]
+}
+
+// Size returns the number of leaves the tree was built from (excluding padding)
+func (t *MerkleTree) Size() int {
+	return t.size
+}
+
+// Depth returns the number of levels between the leaves and the root
+func (t *MerkleTree) Depth() int {
+	return len(t.levels) - 1
+}
+
+// Update replaces the leaf at index and recomputes the Depth() nodes above it
+// Every level is cached on the tree, so only the path to the root is rehashed;
+// proofs for other leaves must be fetched again since their paths may have changed
+func (t *MerkleTree) Update(index int, newLeaf Fr) error {
+	if index < 0 || index >= t.size {
+		return fmt.Errorf("leaf index %d out of range [0, %d)", index, t.size)
+	}
+
+	t.levels[0][index] = newLeaf
+	for h := 1; h < len(t.levels); h++ {
+		index >>= 1
+		t.levels[h][index] = MerkleNode(t.levels[h-1][2*index], t.levels[h-1][2*index+1])
+	}
+	return nil
+}
+
+// Proof returns the sibling path for the leaf at index, ordered from the leaf level upwards
+func (t *MerkleTree) Proof(index int) ([]Fr, error) {
+	if index < 0 || index >= t.size {
+		return nil, fmt.Errorf("leaf index %d out of range [0, %d)", index, t.size)
+	}
+
+	path := make([]Fr, t.Depth())
+	for h := range path {
+		path[h] = t.levels[h][index^1]
+		index >>= 1
+	}
+	return path, nil
+}
+
+// VerifyMerkleProof checks that leaf sits at index under root using the sibling path
+// Bit h of index selects whether the running node is the right (1) or left (0) child at level h
+func VerifyMerkleProof(root, leaf Fr, index int, path []Fr) bool {
+	if index < 0 || index >= 1<<len(path) {
+		return false
+	}
+
+	node := FoldMerklePath(leaf, index, path)
+	return node.Equal(&root)
+}
+
+// FoldMerklePath recomputes the root implied by leaf, its index and its sibling path
+// The running node is combined with path[h] by MerkleNode, as the right child when
+// bit h of index is set and as the left child otherwise. Only the low len(path) bits
+// of index are used; VerifyMerkleProof additionally rejects out-of-range indices
+func FoldMerklePath(leaf Fr, index int, path []Fr) Fr {
+	node := leaf
+	for _, sibling := range path {
+		if index&1 == 1 {
+			node = MerkleNode(sibling, node)
+		} else {
+			node = MerkleNode(node, sibling)
+		}
+		index >>= 1
+	}
+	return node
+}
+
+// VerifyMerkleProofCT is VerifyMerkleProof without branching on the index bits
+// Each level orders (node, sibling) with a constant-time conditional swap, so the
+// sequence of operations does not depend on the leaf position; only len(path) and
+// whether index is in range are observable
+func VerifyMerkleProofCT(root, leaf Fr, index int, path []Fr) bool {
+	if index < 0 || index >= 1<<len(path) {
+		return false
+	}
+
+	node := leaf
+	for h := range path {
+		left, right := node, path[h]
+		cswap(&left, &right, uint64(index>>h)&1)
+		node = MerkleNode(left, right)
+	}
+	return node.EqualCT(&root)
+}
+
+// treeDepth returns ceil(log2(n)), the depth of a perfect tree holding n leaves
+func treeDepth(n int) int {
+	depth := 0
+	for 1<<depth < n {
+		depth++
+	}
+	return depth
+}
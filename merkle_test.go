@@ -0,0 +1,441 @@
+package poseidon2
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// sequentialLeaves returns n leaves holding the values 1..n
+func sequentialLeaves(n int) []Fr {
+	leaves := make([]Fr, n)
+	for i := range leaves {
+		leaves[i] = FromUint64(uint64(i + 1))
+	}
+	return leaves
+}
+
+// TestMerkleProofs checks that every leaf proof verifies against the root
+func TestMerkleProofs(t *testing.T) {
+	for _, n := range []int{1, 2, 3, 5, 8} {
+		leaves := sequentialLeaves(n)
+		tree, err := BuildMerkleTree(leaves)
+		if err != nil {
+			t.Fatalf("BuildMerkleTree(%d leaves) failed: %v", n, err)
+		}
+
+		for i, leaf := range leaves {
+			path, err := tree.Proof(i)
+			if err != nil {
+				t.Fatalf("Proof(%d) failed: %v", i, err)
+			}
+			if !VerifyMerkleProof(tree.Root(), leaf, i, path) {
+				t.Errorf("proof for leaf %d of %d did not verify", i, n)
+			}
+			if VerifyMerkleProof(tree.Root(), FromUint64(999), i, path) {
+				t.Errorf("proof for leaf %d of %d verified a wrong leaf", i, n)
+			}
+		}
+
+		if _, err := tree.Proof(n); err == nil {
+			t.Errorf("Proof(%d) should fail for a %d-leaf tree", n, n)
+		}
+	}
+}
+
+// TestConsistencyProofExtension checks that appending leaves yields a verifiable proof
+func TestConsistencyProofExtension(t *testing.T) {
+	for _, sizes := range [][2]int{{1, 2}, {1, 5}, {2, 3}, {3, 4}, {3, 8}, {4, 7}, {5, 16}, {7, 9}} {
+		oldSize, newSize := sizes[0], sizes[1]
+		leaves := sequentialLeaves(newSize)
+
+		oldTree, err := BuildMerkleTree(leaves[:oldSize])
+		if err != nil {
+			t.Fatal(err)
+		}
+		newTree, err := BuildMerkleTree(leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		proof, err := ConsistencyProof(oldTree, newTree)
+		if err != nil {
+			t.Fatalf("ConsistencyProof(%d, %d) failed: %v", oldSize, newSize, err)
+		}
+
+		oldRoot := oldTree.Root().ToBytes32()
+		newRoot := newTree.Root().ToBytes32()
+		if !VerifyConsistency(oldRoot, newRoot, oldSize, newSize, proof) {
+			t.Errorf("consistency proof %d -> %d did not verify", oldSize, newSize)
+		}
+
+		// A tampered proof must be rejected
+		tampered := append([][32]byte(nil), proof...)
+		tampered[len(tampered)-1][31] ^= 1
+		if VerifyConsistency(oldRoot, newRoot, oldSize, newSize, tampered) {
+			t.Errorf("tampered consistency proof %d -> %d verified", oldSize, newSize)
+		}
+	}
+}
+
+// TestConsistencyProofNonExtension checks that a tree which rewrites history is rejected
+func TestConsistencyProofNonExtension(t *testing.T) {
+	leaves := sequentialLeaves(6)
+	oldTree, err := BuildMerkleTree(leaves[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forked := sequentialLeaves(6)
+	forked[1] = FromUint64(1000)
+	forkedTree, err := BuildMerkleTree(forked)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ConsistencyProof(oldTree, forkedTree); err == nil {
+		t.Error("ConsistencyProof should refuse trees that diverge")
+	}
+
+	// A proof honestly produced for the forked tree must not link it to the old root
+	forkedPrefix, err := BuildMerkleTree(forked[:3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := ConsistencyProof(forkedPrefix, forkedTree)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if VerifyConsistency(oldTree.Root().ToBytes32(), forkedTree.Root().ToBytes32(), 3, 6, proof) {
+		t.Error("consistency proof verified for a non-extension")
+	}
+
+	if _, err := ConsistencyProof(forkedTree, oldTree); err == nil {
+		t.Error("ConsistencyProof should refuse a shrinking tree")
+	}
+}
+
+// TestConsistencyProofEqualSize checks the equal-size edge case
+func TestConsistencyProofEqualSize(t *testing.T) {
+	leaves := sequentialLeaves(4)
+	a, err := BuildMerkleTree(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := BuildMerkleTree(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proof, err := ConsistencyProof(a, b)
+	if err != nil {
+		t.Fatalf("ConsistencyProof on equal trees failed: %v", err)
+	}
+	if len(proof) != 0 {
+		t.Errorf("equal-size proof should be empty, got %d nodes", len(proof))
+	}
+
+	root := a.Root().ToBytes32()
+	if !VerifyConsistency(root, root, 4, 4, proof) {
+		t.Error("equal-size consistency should verify for identical roots")
+	}
+
+	other := Hash(FromUint64(7)).ToBytes32()
+	if VerifyConsistency(root, other, 4, 4, proof) {
+		t.Error("equal-size consistency should fail for different roots")
+	}
+}
+
+// TestLeafHashSeparation checks leaf digests are separated from internal node hashing
+func TestLeafHashSeparation(t *testing.T) {
+	data := make([]byte, 62)
+	for i := range data {
+		data[i] = byte(i + 1)
+	}
+	var left, right [32]byte
+	copy(left[1:], data[:31])
+	copy(right[1:], data[31:])
+
+	leaf := LeafHash(DomainGeneric, data)
+	node := MerkleNode(FromBytes(left), FromBytes(right))
+	if leaf.Equal(&node) {
+		t.Error("LeafHash collided with MerkleNode over the same bytes")
+	}
+	compressed := Compress2(FromBytes(left), FromBytes(right))
+	if leaf.Equal(&compressed) {
+		t.Error("LeafHash collided with Compress2 over the same bytes")
+	}
+
+	pair := FromBytes(HashPair(left, right))
+	if leaf.Equal(&pair) {
+		t.Error("LeafHash collided with HashPair over the same bytes")
+	}
+
+	bytesDigest, err := HashBytes(DomainGeneric, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaf.ToBytes32() == bytesDigest {
+		t.Error("LeafHash should differ from HashBytes with the same tag")
+	}
+
+	// A leaf whose bytes equal a node digest still hashes to something else
+	nodeBytes := node.ToBytes32()
+	if got := LeafHash(DomainGeneric, nodeBytes[:]); got.Equal(&node) {
+		t.Error("LeafHash of a node digest returned the node digest")
+	}
+
+	other := LeafHash(DomainPOETNode, data)
+	if leaf.Equal(&other) {
+		t.Error("LeafHash should depend on the domain tag")
+	}
+
+	again := LeafHash(DomainGeneric, data)
+	if !leaf.Equal(&again) {
+		t.Error("LeafHash is not deterministic")
+	}
+
+	empty := LeafHash(DomainGeneric, nil)
+	zeroByte := LeafHash(DomainGeneric, []byte{0})
+	if empty.Equal(&zeroByte) {
+		t.Error("LeafHash of empty data should differ from a single zero byte")
+	}
+}
+
+// TestVerifyMerkleProofCT checks the constant-time verifier agrees with VerifyMerkleProof
+func TestVerifyMerkleProofCT(t *testing.T) {
+	rng := rand.New(rand.NewSource(31))
+	for _, n := range []int{1, 2, 5, 16, 33} {
+		leaves := make([]Fr, n)
+		for i := range leaves {
+			leaves[i] = randomFr(rng)
+		}
+		tree, err := BuildMerkleTree(leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := tree.Root()
+
+		for i, leaf := range leaves {
+			path, err := tree.Proof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			wrong := randomFr(rng)
+			for _, index := range []int{i, i ^ 1, -1, 1 << len(path)} {
+				for _, candidate := range []Fr{leaf, wrong} {
+					want := VerifyMerkleProof(root, candidate, index, path)
+					if got := VerifyMerkleProofCT(root, candidate, index, path); got != want {
+						t.Errorf("n=%d leaf=%d index=%d: CT verifier returned %v, want %v", n, i, index, got, want)
+					}
+				}
+			}
+			if !VerifyMerkleProofCT(root, leaf, i, path) {
+				t.Errorf("CT proof for leaf %d of %d did not verify", i, n)
+			}
+		}
+	}
+}
+
+// TestMerkleSmallTrees checks the documented conventions for 0, 1 and 2 leaves
+func TestMerkleSmallTrees(t *testing.T) {
+	for _, leaves := range [][]Fr{nil, {}} {
+		if tree, err := BuildMerkleTree(leaves); err == nil || tree != nil {
+			t.Errorf("BuildMerkleTree(%v) = %v, %v; want nil tree and an error", leaves, tree, err)
+		}
+	}
+
+	leaf := FromUint64(42)
+	single, err := BuildMerkleTree([]Fr{leaf})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := single.Root(); !root.Equal(&leaf) {
+		t.Errorf("single-leaf root = %s, want the leaf %s", root.String(), leaf.String())
+	}
+	if single.Depth() != 0 || single.Size() != 1 {
+		t.Errorf("single-leaf tree has depth %d and size %d, want 0 and 1", single.Depth(), single.Size())
+	}
+	path, err := single.Proof(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(path) != 0 || !VerifyMerkleProof(single.Root(), leaf, 0, path) {
+		t.Error("single-leaf proof should be empty and verify")
+	}
+
+	a, b := FromUint64(1), FromUint64(2)
+	pair, err := BuildMerkleTree([]Fr{a, b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root, want := pair.Root(), MerkleNode(a, b); !root.Equal(&want) {
+		t.Errorf("two-leaf root = %s, want MerkleNode(a, b) = %s", root.String(), want.String())
+	}
+}
+
+// TestMerkleDomains checks leaf and node hashing of identical content differ
+func TestMerkleDomains(t *testing.T) {
+	if DomainMerkleLeaf == DomainMerkleNode {
+		t.Fatal("leaf and node domains must differ")
+	}
+
+	a, b := FromUint64(1), FromUint64(2)
+	node := MerkleNode(a, b)
+
+	// The same two elements hashed as a leaf under the leaf domain
+	leafHasher := NewHasherWithIV(FromUint64(uint64(DomainMerkleLeaf)))
+	leafHasher.AbsorbMany([]Fr{a, b})
+	if leaf := leafHasher.Finalize(); leaf.Equal(&node) {
+		t.Error("leaf and node hashing of the same elements collide")
+	}
+
+	// And the same bytes through LeafHash
+	ab, bb := a.ToBytes32(), b.ToBytes32()
+	if leaf := LeafHash(DomainGeneric, append(ab[:], bb[:]...)); leaf.Equal(&node) {
+		t.Error("LeafHash of the children's bytes equals their parent node")
+	}
+
+	// MerkleNode is a single permutation with the node domain in the capacity
+	state := [T]Fr{a, b, FromUint64(uint64(DomainMerkleNode))}
+	ProductionPermutation(&state)
+	if !node.Equal(&state[0]) {
+		t.Error("MerkleNode does not seed the capacity with DomainMerkleNode")
+	}
+	if compressed := Compress2(a, b); node.Equal(&compressed) {
+		t.Error("MerkleNode should differ from Compress2")
+	}
+}
+
+// TestHashRoots checks root aggregation is order-sensitive and separated from other hashing
+func TestHashRoots(t *testing.T) {
+	a, b := FromUint64(11), FromUint64(22)
+	ab := HashRoots(DomainGeneric, a, b)
+	ba := HashRoots(DomainGeneric, b, a)
+	if ab.Equal(&ba) {
+		t.Error("reordering roots should change HashRoots")
+	}
+
+	again := HashRoots(DomainGeneric, a, b)
+	if !ab.Equal(&again) {
+		t.Error("HashRoots is not deterministic")
+	}
+
+	others := map[string]Fr{
+		"Compress2":         Compress2(a, b),
+		"MerkleNode":        MerkleNode(a, b),
+		"Hash":              Hash(a, b),
+		"HashMany":          HashMany(DomainGeneric, a, b),
+		"HashWithLengthTag": HashWithLengthTag(a, b),
+		"other tag":         HashRoots(DomainPOETNode, a, b),
+		"trailing zero":     HashRoots(DomainGeneric, a, b, Zero()),
+	}
+	for name, other := range others {
+		if ab.Equal(&other) {
+			t.Errorf("HashRoots collided with %s", name)
+		}
+	}
+
+	// A single root is not passed through, so it cannot pose as a leaf or node
+	single := HashRoots(DomainGeneric, a)
+	if single.Equal(&a) {
+		t.Error("HashRoots of one root returned the root itself")
+	}
+
+	if tag, ok := LookupDomain("roots"); !ok || tag != domainRoots {
+		t.Error("root aggregation tag is not reserved in the domain registry")
+	}
+}
+
+// TestMerkleTreeUpdate checks Update against a rebuilt tree and that other proofs still verify
+func TestMerkleTreeUpdate(t *testing.T) {
+	rng := rand.New(rand.NewSource(79))
+	for _, n := range []int{1, 2, 5, 8, 13} {
+		leaves := make([]Fr, n)
+		for i := range leaves {
+			leaves[i] = randomFr(rng)
+		}
+		tree, err := BuildMerkleTree(leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, index := range []int{0, n / 2, n - 1} {
+			leaves[index] = randomFr(rng)
+			if err := tree.Update(index, leaves[index]); err != nil {
+				t.Fatalf("n=%d: Update(%d) failed: %v", n, index, err)
+			}
+
+			fresh, err := BuildMerkleTree(leaves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if root, want := tree.Root(), fresh.Root(); !root.Equal(&want) {
+				t.Errorf("n=%d: root after Update(%d) differs from a rebuilt tree", n, index)
+			}
+
+			for i, leaf := range leaves {
+				path, err := tree.Proof(i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !VerifyMerkleProof(tree.Root(), leaf, i, path) {
+					t.Errorf("n=%d: proof for leaf %d fails after Update(%d)", n, i, index)
+				}
+			}
+		}
+
+		for _, index := range []int{-1, n, n + 1} {
+			if err := tree.Update(index, Zero()); err == nil {
+				t.Errorf("n=%d: Update(%d) should fail", n, index)
+			}
+		}
+	}
+}
+
+// TestFoldMerklePath checks the folded path reproduces BuildMerkleTree roots
+func TestFoldMerklePath(t *testing.T) {
+	rng := rand.New(rand.NewSource(95))
+	for _, n := range []int{1, 2, 3, 7, 16} {
+		leaves := make([]Fr, n)
+		for i := range leaves {
+			leaves[i] = randomFr(rng)
+		}
+		tree, err := BuildMerkleTree(leaves)
+		if err != nil {
+			t.Fatal(err)
+		}
+		root := tree.Root()
+
+		for i, leaf := range leaves {
+			path, err := tree.Proof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := FoldMerklePath(leaf, i, path); !got.Equal(&root) {
+				t.Errorf("n=%d: folding leaf %d does not reproduce the root", n, i)
+			}
+			if n > 1 {
+				if got := FoldMerklePath(leaf, i^1, path); got.Equal(&root) {
+					t.Errorf("n=%d: folding leaf %d with the wrong index reproduced the root", n, i)
+				}
+			}
+		}
+	}
+}
+
+// TestMerkleNodeBytes checks the byte-oriented node compression reproduces tree roots
+func TestMerkleNodeBytes(t *testing.T) {
+	leaves := []Fr{FromUint64(1), FromUint64(2)}
+	tree, err := BuildMerkleTree(leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := tree.Root()
+	if got := MerkleNodeBytes(leaves[0].ToBytes32(), leaves[1].ToBytes32()); got != root.ToBytes32() {
+		t.Error("MerkleNodeBytes does not reproduce the tree root")
+	}
+	if got := HashPair(leaves[0].ToBytes32(), leaves[1].ToBytes32()); got == root.ToBytes32() {
+		t.Error("HashPair unexpectedly matches the tree root")
+	}
+}
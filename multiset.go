@@ -0,0 +1,50 @@
+package poseidon2
+
+// domainMultiset separates per-element multiset hashes from every other hashing mode
+const domainMultiset Domain = 0x53474d53 // "SGMS"
+
+// multisetElementHash maps one element to its multiplicative factor
+// It is a sponge with domainMultiset in the capacity absorbing the element
+func multisetElementHash(element Fr) Fr {
+	hasher := NewHasherWithIV(FromUint64(uint64(domainMultiset)))
+	hasher.Absorb(element)
+	return hasher.Finalize()
+}
+
+// MultisetHash returns the product of the per-element hashes of elements
+// The product is independent of order and counts multiplicity, and it can be
+// maintained incrementally: MultisetAdd multiplies one factor in and MultisetRemove
+// divides it out. The empty multiset hashes to One()
+//
+// It is NOT collision-resistant against adversarially chosen elements. The product
+// lives in the multiplicative group of Fr, whose order r-1 is highly smooth
+// (2^28 * 3^2 * 13 * 29 * ...), so discrete logs are cheap by Pohlig-Hellman and
+// distinct multisets with equal hashes can be found by lattice reduction. Use it
+// only for non-adversarial bookkeeping, such as checking that two honestly
+// maintained collections agree; use HashSet when inputs may be attacker-controlled
+func MultisetHash(elements []Fr) Fr {
+	acc := One()
+	for _, element := range elements {
+		acc = MultisetAdd(acc, element)
+	}
+	return acc
+}
+
+// MultisetAdd returns the accumulator acc with element added (one hash and one Mul)
+func MultisetAdd(acc, element Fr) Fr {
+	factor := multisetElementHash(element)
+	var result Fr
+	result.Mul(&acc, &factor)
+	return result
+}
+
+// MultisetRemove returns the accumulator acc with one copy of element removed
+// It multiplies by the inverse of the element's factor and so undoes MultisetAdd;
+// removing an element that was never added yields a value no real multiset hashes to
+func MultisetRemove(acc, element Fr) Fr {
+	factor := multisetElementHash(element)
+	var inv, result Fr
+	inv.Inverse(&factor)
+	result.Mul(&acc, &inv)
+	return result
+}
@@ -0,0 +1,187 @@
+package poseidon2
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// Supported state widths for Permutation
+const (
+	MinPermutationWidth = 2
+	MaxPermutationWidth = 24
+)
+
+// Permutation is a Poseidon2 permutation over a state of width t
+// Round constants and the MDS matrix are derived exactly as for the production
+// instance with t and d substituted into the seeds, so NewPermutation(T) computes
+// ProductionPermutation. Round counts are the production ones (F=8, P=56)
+type Permutation struct {
+	t              int
+	d              int    // S-box exponent, validated by NewPermutationWithDegree
+	roundConstants [][]Fr // [round][pos], zero beyond pos 0 in partial rounds
+	mds            [][]Fr
+}
+
+// NewPermutation derives the permutation for state width t with the production S-box x^5
+func NewPermutation(t int) (*Permutation, error) {
+	return NewPermutationWithDegree(t, D)
+}
+
+// NewPermutationWithDegree derives the permutation for state width t and S-box x^d
+// x^d only permutes Fr when gcd(d, r-1) = 1; for BN254 this excludes d = 3,
+// since 3 divides r-1, leaving d = 5 and d = 7 as the usual choices
+func NewPermutationWithDegree(t, d int) (*Permutation, error) {
+	if t < MinPermutationWidth || t > MaxPermutationWidth {
+		return nil, fmt.Errorf("unsupported state width %d (must be %d..%d)", t, MinPermutationWidth, MaxPermutationWidth)
+	}
+	if err := validateSBoxDegree(d); err != nil {
+		return nil, err
+	}
+	if err := ValidateSecurityParameters(t, d, FULL_ROUNDS, PARTIAL_ROUNDS, 254); err != nil {
+		return nil, fmt.Errorf("width %d: %w", t, err)
+	}
+	
+	return &Permutation{
+		t:              t,
+		d:              d,
+		roundConstants: deriveRoundConstants(t, d),
+		mds:            deriveMDSMatrix(t),
+	}, nil
+}
+
+// validateSBoxDegree checks that x^d is a bijection on Fr, i.e. d > 1 and gcd(d, r-1) = 1
+func validateSBoxDegree(d int) error {
+	if d < 2 {
+		return fmt.Errorf("S-box degree %d must be at least 2", d)
+	}
+	rMinusOne := new(big.Int).Sub(limbsToBigInt(&rModulus), big.NewInt(1))
+	if gcd := new(big.Int).GCD(nil, nil, big.NewInt(int64(d)), rMinusOne); gcd.Cmp(big.NewInt(1)) != 0 {
+		return fmt.Errorf("S-box degree %d is not a permutation of Fr: gcd(%d, r-1) = %s", d, d, gcd)
+	}
+	return nil
+}
+
+// sBox applies x^d for the permutation's degree
+func (p *Permutation) sBox(x *Fr) {
+	if p.d == 5 {
+		*x = SBox(*x)
+		return
+	}
+	x.Exp(x, big.NewInt(int64(p.d)))
+}
+
+// Width returns the state width t
+func (p *Permutation) Width() int {
+	return p.t
+}
+
+// Degree returns the S-box exponent d
+func (p *Permutation) Degree() int {
+	return p.d
+}
+
+// Apply permutes state in place
+// A state whose length is not the permutation width is rejected before any element is touched
+func (p *Permutation) Apply(state []Fr) error {
+	if len(state) != p.t {
+		return fmt.Errorf("state has %d elements, permutation width is %d", len(state), p.t)
+	}
+	
+	temp := make([]Fr, p.t)
+	for round := 0; round < TOTAL_ROUNDS; round++ {
+		if round < FULL_ROUNDS/2 || round >= FULL_ROUNDS/2+PARTIAL_ROUNDS {
+			for i := range state {
+				state[i].Add(&state[i], &p.roundConstants[round][i])
+				p.sBox(&state[i])
+			}
+		} else {
+			state[0].Add(&state[0], &p.roundConstants[round][0])
+			p.sBox(&state[0])
+		}
+		p.applyMDS(state, temp)
+	}
+	return nil
+}
+
+// applyMDS sets state = mds * state using temp as scratch space
+func (p *Permutation) applyMDS(state, temp []Fr) {
+	for i := range temp {
+		temp[i] = Zero()
+		for j := range state {
+			var product Fr
+			product.Mul(&p.mds[i][j], &state[j])
+			temp[i].Add(&temp[i], &product)
+		}
+	}
+	copy(state, temp)
+}
+
+// permutationCache holds the Permutation for each width Permute has been called with
+var permutationCache = struct {
+	sync.Mutex
+	byWidth map[int]*Permutation
+}{byWidth: make(map[int]*Permutation)}
+
+// Permute applies the Poseidon2 permutation of width len(state) to state in place
+// The permutation for each width is derived on first use and cached; widths
+// NewPermutation rejects return its error. For len(state) == T this equals
+// ProductionPermutation
+func Permute(state []Fr) error {
+	perm, err := permutationForWidth(len(state))
+	if err != nil {
+		return err
+	}
+	return perm.Apply(state)
+}
+
+// permutationForWidth returns the cached Permutation for width t, creating it if needed
+func permutationForWidth(t int) (*Permutation, error) {
+	permutationCache.Lock()
+	defer permutationCache.Unlock()
+	
+	if perm, ok := permutationCache.byWidth[t]; ok {
+		return perm, nil
+	}
+	perm, err := NewPermutation(t)
+	if err != nil {
+		return nil, err
+	}
+	permutationCache.byWidth[t] = perm
+	return perm, nil
+}
+
+// deriveRoundConstants generates the round constants for width t and S-box degree d
+func deriveRoundConstants(t, d int) [][]Fr {
+	seed := []byte(fmt.Sprintf("Poseidon2_bn256_r_t%d_d%d_F8_P56", t, d))
+	
+	constants := make([][]Fr, TOTAL_ROUNDS)
+	for round := range constants {
+		constants[round] = make([]Fr, t) // Partial rounds keep zeros beyond position 0
+		if round < FULL_ROUNDS/2 || round >= FULL_ROUNDS/2+PARTIAL_ROUNDS {
+			for pos := 0; pos < t; pos++ {
+				constants[round][pos] = generateConstant(seed, round, pos)
+			}
+		} else {
+			constants[round][0] = generateConstant(seed, round, 0)
+		}
+	}
+	return constants
+}
+
+// deriveMDSMatrix generates the t x t mixing matrix for width t
+func deriveMDSMatrix(t int) [][]Fr {
+	seed := []byte(fmt.Sprintf("Poseidon2_MDS_bn256_r_t%d", t))
+	one := One()
+	
+	matrix := make([][]Fr, t)
+	for i := range matrix {
+		matrix[i] = make([]Fr, t)
+		for j := range matrix[i] {
+			elementSeed := append(append([]byte(nil), seed...), byte(i), byte(j))
+			matrix[i][j] = generateConstant(elementSeed, i, j)
+			matrix[i][j].Add(&matrix[i][j], &one)
+		}
+	}
+	return matrix
+}
@@ -3,6 +3,7 @@ package poseidon2
 import (
 	"crypto/sha256"
 	"encoding/binary"
+	"fmt"
 )
 
 // Production Poseidon2 permutation parameters
@@ -25,7 +26,40 @@ func init() {
 }
 
 // ProductionPermutation applies the full Poseidon2 permutation
+// For t=3 the hand-unrolled version is used; other widths take the generic round loops
 func ProductionPermutation(state *[T]Fr) {
+	if instrumentCalls {
+		permutationCalls.Add(1)
+	}
+	if T == 3 {
+		productionPermutationUnrolled(state)
+		return
+	}
+	productionPermutationGeneric(state)
+}
+
+// PermuteCopy returns the permutation of state, leaving the caller's array untouched
+// The array is passed by value, so this is ProductionPermutation applied to a copy
+func PermuteCopy(state [T]Fr) [T]Fr {
+	ProductionPermutation(&state)
+	return state
+}
+
+// ProductionPermutationChecked is ProductionPermutation that first verifies every
+// state element is canonical (IsValid). On failure it returns an error naming the
+// first bad index and leaves state untouched
+func ProductionPermutationChecked(state *[T]Fr) error {
+	for i := range state {
+		if !state[i].IsValid() {
+			return fmt.Errorf("state element %d is not canonical (limbs %x are not below the modulus)", i, [4]uint64(state[i]))
+		}
+	}
+	ProductionPermutation(state)
+	return nil
+}
+
+// productionPermutationGeneric applies the permutation with the width-generic round loops
+func productionPermutationGeneric(state *[T]Fr) {
 	// First F/2 full rounds (4 rounds)
 	for round := 0; round < FULL_ROUNDS/2; round++ {
 		fullRound(state, round)
@@ -42,6 +76,86 @@ func ProductionPermutation(state *[T]Fr) {
 	}
 }
 
+// productionPermutationUnrolled is ProductionPermutation for t=3 with the state held
+// in locals, the S-box inlined and the 3x3 MDS product written out, avoiding the
+// per-element loops and the temporary copy in applyMDS
+func productionPermutationUnrolled(state *[T]Fr) {
+	s0, s1, s2 := state[0], state[1], state[2]
+	var x2, x4 Fr
+	
+	for round := 0; round < TOTAL_ROUNDS; round++ {
+		rc := &roundConstants[round]
+		if round < FULL_ROUNDS/2 || round >= FULL_ROUNDS/2+PARTIAL_ROUNDS {
+			s0.Add(&s0, &rc[0])
+			s1.Add(&s1, &rc[1])
+			s2.Add(&s2, &rc[2])
+			
+			x2.Square(&s0)
+			x4.Square(&x2)
+			s0.Mul(&x4, &s0)
+			x2.Square(&s1)
+			x4.Square(&x2)
+			s1.Mul(&x4, &s1)
+			x2.Square(&s2)
+			x4.Square(&x2)
+			s2.Mul(&x4, &s2)
+		} else {
+			s0.Add(&s0, &rc[0])
+			
+			x2.Square(&s0)
+			x4.Square(&x2)
+			s0.Mul(&x4, &s0)
+		}
+		mdsUnrolled(&s0, &s1, &s2)
+	}
+	
+	state[0], state[1], state[2] = s0, s1, s2
+}
+
+// mdsUnrolled multiplies (s0, s1, s2) by the 3x3 MDS matrix in place
+func mdsUnrolled(s0, s1, s2 *Fr) {
+	m := &mdsMatrix
+	var t0, t1, t2, p Fr
+	
+	t0.Mul(&m[0][0], s0)
+	p.Mul(&m[0][1], s1)
+	t0.Add(&t0, &p)
+	p.Mul(&m[0][2], s2)
+	t0.Add(&t0, &p)
+	
+	t1.Mul(&m[1][0], s0)
+	p.Mul(&m[1][1], s1)
+	t1.Add(&t1, &p)
+	p.Mul(&m[1][2], s2)
+	t1.Add(&t1, &p)
+	
+	t2.Mul(&m[2][0], s0)
+	p.Mul(&m[2][1], s1)
+	t2.Add(&t2, &p)
+	p.Mul(&m[2][2], s2)
+	t2.Add(&t2, &p)
+	
+	*s0, *s1, *s2 = t0, t1, t2
+}
+
+// ProductionPermutationTrace applies ProductionPermutation to state and returns a
+// snapshot of the state after every round (constants, S-box and MDS applied)
+// Snapshot i is the state after round i, so the last one equals the final output
+func ProductionPermutationTrace(state *[T]Fr) [][T]Fr {
+	trace := make([][T]Fr, 0, TOTAL_ROUNDS)
+	
+	for round := 0; round < TOTAL_ROUNDS; round++ {
+		if round < FULL_ROUNDS/2 || round >= FULL_ROUNDS/2+PARTIAL_ROUNDS {
+			fullRound(state, round)
+		} else {
+			partialRound(state, round)
+		}
+		trace = append(trace, *state)
+	}
+	
+	return trace
+}
+
 // fullRound performs a complete Poseidon2 round
 func fullRound(state *[T]Fr, round int) {
 	// Add round constants
@@ -70,15 +184,22 @@ func partialRound(state *[T]Fr, round int) {
 	applyMDS(state)
 }
 
-// sBoxProd computes x^5 efficiently: x^5 = x * (x^2)^2
-func sBoxProd(x *Fr) Fr {
+// SBox is the degree-5 S-box applied to every state element in full rounds
+// and to state[0] in partial rounds, computed as x^5 = x * (x^2)^2
+// Circuit authors should constrain exactly this map
+func SBox(x Fr) Fr {
 	var x2, x4, result Fr
-	x2.Square(x)        // x^2
+	x2.Square(&x)       // x^2
 	x4.Square(&x2)      // x^4
-	result.Mul(&x4, x)  // x^5
+	result.Mul(&x4, &x) // x^5
 	return result
 }
 
+// sBoxProd computes x^5 efficiently: x^5 = x * (x^2)^2
+func sBoxProd(x *Fr) Fr {
+	return SBox(*x)
+}
+
 // applyMDS applies MDS matrix multiplication
 func applyMDS(state *[T]Fr) {
 	var temp [T]Fr
@@ -99,41 +220,82 @@ func applyMDS(state *[T]Fr) {
 
 // generateRoundConstants creates deterministic round constants
 func generateRoundConstants() {
-	seed := []byte("Poseidon2_bn256_r_t3_d5_F8_P56")
+	constants := deriveRoundConstants(T, D)
+	for round := 0; round < TOTAL_ROUNDS; round++ {
+		copy(roundConstants[round][:], constants[round])
+	}
+}
+
+// generateMDSMatrix creates the seed-derived mixing matrix
+// Hashed entries are not MDS by construction; IsMDS verifies the property for this seed
+func generateMDSMatrix() {
+	matrix := deriveMDSMatrix(T)
+	for i := 0; i < T; i++ {
+		copy(mdsMatrix[i][:], matrix[i])
+	}
+}
+
+// ParameterFingerprint identifies the exact hash function this build implements
+// It is SHA-256 over t, d, F and P (each as 8-byte big-endian), followed by every
+// roundConstants[round][pos] (including partial-round zeros) and every mdsMatrix[i][j]
+// in row-major order, each as canonical 32-byte big-endian. Applications storing
+// digests can pin this value to detect an incompatible change in constant generation
+func ParameterFingerprint() [32]byte {
+	h := sha256.New()
+	
+	var word [8]byte
+	for _, param := range []uint64{T, D, FULL_ROUNDS, PARTIAL_ROUNDS} {
+		binary.BigEndian.PutUint64(word[:], param)
+		h.Write(word[:])
+	}
+	
+	for round := 0; round < TOTAL_ROUNDS; round++ {
+		for pos := 0; pos < T; pos++ {
+			b := roundConstants[round][pos].ToBytes32()
+			h.Write(b[:])
+		}
+	}
+	for i := 0; i < T; i++ {
+		for j := 0; j < T; j++ {
+			b := mdsMatrix[i][j].ToBytes32()
+			h.Write(b[:])
+		}
+	}
 	
+	var fingerprint [32]byte
+	copy(fingerprint[:], h.Sum(nil))
+	return fingerprint
+}
+
+// ExportRoundConstants returns the round constants as canonical 0x-prefixed hex in
+// application order, in the flat-list layout of the Poseidon2 reference constant files:
+// T constants for each full round and one for each partial round (the unused
+// partial-round zeros are omitted), giving FULL_ROUNDS*T + PARTIAL_ROUNDS entries
+func ExportRoundConstants() []string {
+	out := make([]string, 0, FULL_ROUNDS*T+PARTIAL_ROUNDS)
 	for round := 0; round < TOTAL_ROUNDS; round++ {
 		if round < FULL_ROUNDS/2 || round >= FULL_ROUNDS/2+PARTIAL_ROUNDS {
-			// Full round: generate constants for all positions
 			for pos := 0; pos < T; pos++ {
-				roundConstants[round][pos] = generateConstant(seed, round, pos)
+				out = append(out, roundConstants[round][pos].Hex())
 			}
 		} else {
-			// Partial round: only first position gets constant
-			roundConstants[round][0] = generateConstant(seed, round, 0)
-			roundConstants[round][1] = Zero()
-			roundConstants[round][2] = Zero()
+			out = append(out, roundConstants[round][0].Hex())
 		}
 	}
+	return out
 }
 
-// generateMDSMatrix creates a simple but secure MDS matrix
-func generateMDSMatrix() {
-	seed := []byte("Poseidon2_MDS_bn256_r_t3")
-	
-	// Generate a Cauchy matrix which is guaranteed to be MDS
-	// For small t=3, we can use a simple but secure construction
+// ExportMatrices returns the mixing matrix as rows of canonical 0x-prefixed hex
+// The same matrix is applied in full and partial rounds, so there is one T x T matrix
+func ExportMatrices() [][]string {
+	out := make([][]string, T)
 	for i := 0; i < T; i++ {
+		out[i] = make([]string, T)
 		for j := 0; j < T; j++ {
-			// Use Cauchy matrix construction: M[i][j] = 1/(x_i + y_j)
-			// where x_i, y_j are distinct field elements
-			elementSeed := append(seed, byte(i), byte(j))
-			mdsMatrix[i][j] = generateConstant(elementSeed, i, j)
-			
-			// Ensure non-zero elements (add 1 to avoid zero)
-			one := FromUint64(1)
-			mdsMatrix[i][j].Add(&mdsMatrix[i][j], &one)
+			out[i][j] = mdsMatrix[i][j].Hex()
 		}
 	}
+	return out
 }
 
 // generateConstant creates a field element from seed material
@@ -0,0 +1,13 @@
+package poseidon2
+
+// EvalPoly evaluates the polynomial with the given coefficients at x
+// coeffs[i] is the coefficient of x^i; an empty slice is the zero polynomial
+// Horner's rule costs one Mul and one Add per coefficient, all in Montgomery form
+func EvalPoly(coeffs []Fr, x Fr) Fr {
+	var result Fr
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result.Mul(&result, &x)
+		result.Add(&result, &coeffs[i])
+	}
+	return result
+}
@@ -3,9 +3,12 @@ package poseidon2
 import (
 	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -16,10 +19,11 @@ type PermutationKATVector struct {
 	Expected    []string `json:"expected"`
 }
 
+// HashKATVector decodes its hex strings straight into field elements via Fr's JSON support
 type HashKATVector struct {
-	Description string   `json:"description"`
-	Input       []string `json:"input"`
-	Expected    string   `json:"expected"`
+	Description string `json:"description"`
+	Input       []Fr   `json:"input"`
+	Expected    Fr     `json:"expected"`
 }
 
 type Compress2KATVector struct {
@@ -36,6 +40,12 @@ type BytesHashKATVector struct {
 	Expected    string `json:"expected"`
 }
 
+type MerkleKATVector struct {
+	Description string   `json:"description"`
+	Leaves      []string `json:"leaves"`
+	Root        string   `json:"root"`
+}
+
 type PoseidonKATVectors struct {
 	Poseidon2TestVectors struct {
 		FieldModulus     string                   `json:"field_modulus"`
@@ -44,12 +54,19 @@ type PoseidonKATVectors struct {
 		HashTests        []HashKATVector         `json:"hash_tests"`
 		Compress2Tests   []Compress2KATVector    `json:"compress2_tests"`
 		BytesHashTests   []BytesHashKATVector    `json:"bytes_hash_tests"`
+		LengthTagTests   []HashKATVector         `json:"length_tag_hash_tests"`
+		MerkleTests      []MerkleKATVector       `json:"merkle_tests"`
 	} `json:"poseidon2_test_vectors"`
 }
 
 // loadKATVectors loads the KAT vectors from JSON file
 func loadKATVectors(t *testing.T) *PoseidonKATVectors {
-	file, err := os.Open("kat/kat.json")
+	return loadKATVectorsFrom(t, "kat/kat.json")
+}
+
+// loadKATVectorsFrom loads KAT vectors from the JSON file at path
+func loadKATVectorsFrom(t *testing.T, path string) *PoseidonKATVectors {
+	file, err := os.Open(path)
 	if err != nil {
 		t.Fatalf("Failed to open KAT vectors file: %v", err)
 	}
@@ -69,6 +86,81 @@ func loadKATVectors(t *testing.T) *PoseidonKATVectors {
 	return &vectors
 }
 
+// permutationKATCase is a permutation vector decoded into field elements
+type permutationKATCase struct {
+	Description string
+	Input       [T]Fr
+	Expected    [T]Fr
+}
+
+// loadKATVectorsLE loads the permutation vectors at path, reading every hex value
+// as little-endian (first byte least significant) or big-endian as requested
+func loadKATVectorsLE(t *testing.T, path string, littleEndian bool) []permutationKATCase {
+	vectors := loadKATVectorsFrom(t, path)
+	
+	cases := make([]permutationKATCase, 0, len(vectors.Poseidon2TestVectors.PermutationTests))
+	for _, tv := range vectors.Poseidon2TestVectors.PermutationTests {
+		c, err := decodePermutationKAT(tv, littleEndian)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		cases = append(cases, c)
+	}
+	return cases
+}
+
+// decodePermutationKAT parses one permutation vector in the given byte order
+func decodePermutationKAT(tv PermutationKATVector, littleEndian bool) (permutationKATCase, error) {
+	c := permutationKATCase{Description: tv.Description}
+	if len(tv.Input) != T || len(tv.Expected) != T {
+		return c, fmt.Errorf("state has %d inputs and %d outputs, want %d", len(tv.Input), len(tv.Expected), T)
+	}
+	for i := 0; i < T; i++ {
+		var err error
+		if c.Input[i], err = hexToFrOrder(tv.Input[i], littleEndian); err != nil {
+			return c, err
+		}
+		if c.Expected[i], err = hexToFrOrder(tv.Expected[i], littleEndian); err != nil {
+			return c, err
+		}
+	}
+	return c, nil
+}
+
+// permutationKATPasses reports whether the permutation maps c.Input to c.Expected
+func permutationKATPasses(c permutationKATCase) bool {
+	state := c.Input
+	ProductionPermutation(&state)
+	return state == c.Expected
+}
+
+// checkPermutationKATOrder checks the vectors at path in the given byte order
+// Each failure is described, noting when the vector would pass in the opposite byte order
+func checkPermutationKATOrder(t *testing.T, path string, littleEndian bool) []string {
+	order, other := "big-endian", "little-endian"
+	if littleEndian {
+		order, other = other, order
+	}
+	
+	var failures []string
+	for _, tv := range loadKATVectorsFrom(t, path).Poseidon2TestVectors.PermutationTests {
+		c, err := decodePermutationKAT(tv, littleEndian)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		if permutationKATPasses(c) {
+			continue
+		}
+		
+		msg := fmt.Sprintf("%q does not match when read as %s", tv.Description, order)
+		if flipped, err := decodePermutationKAT(tv, !littleEndian); err == nil && permutationKATPasses(flipped) {
+			msg += fmt.Sprintf(", but matches as %s: the vectors use the other byte order", other)
+		}
+		failures = append(failures, msg)
+	}
+	return failures
+}
+
 // TestPoseidon2PermutationKAT tests the Poseidon2 permutation against known answer test vectors
 func TestPoseidon2PermutationKAT(t *testing.T) {
 	vectors := loadKATVectors(t)
@@ -119,37 +211,86 @@ func TestPoseidon2HashKAT(t *testing.T) {
 
 	for _, tv := range vectors.Poseidon2TestVectors.HashTests {
 		t.Run(tv.Description, func(t *testing.T) {
-			// Parse input elements
-			var inputElements []Fr
-			if len(tv.Input) > 0 {
-				var err error
-				inputElements, err = hexSliceToFrSlice(tv.Input)
-				if err != nil {
-					t.Fatalf("Failed to parse input elements: %v", err)
-				}
-			}
+			// Compute hash using actual implementation
+			computedResult := Hash(tv.Input...)
 
-			// Parse expected result
-			expectedResult, err := hexToFr(tv.Expected)
-			if err != nil {
-				t.Fatalf("Failed to parse expected result: %v", err)
+			// Compare results
+			if !computedResult.Equal(&tv.Expected) {
+				t.Errorf("Hash result does not match.\nExpected: %s\nGot:      %s",
+					frToHex(tv.Expected), frToHex(computedResult))
 			}
+		})
+	}
+}
 
-			// Compute hash using actual implementation
-			var computedResult Fr
-			if len(inputElements) == 0 {
-				computedResult = Hash()
-			} else {
-				computedResult = Hash(inputElements...)
+// TestPoseidon2LengthTagHashKAT tests HashWithLengthTag against KAT vectors
+func TestPoseidon2LengthTagHashKAT(t *testing.T) {
+	vectors := loadKATVectors(t)
+	
+	if len(vectors.Poseidon2TestVectors.LengthTagTests) == 0 {
+		t.Fatal("No length-tag hash test vectors found")
+	}
+	
+	for _, tv := range vectors.Poseidon2TestVectors.LengthTagTests {
+		t.Run(tv.Description, func(t *testing.T) {
+			computedResult := HashWithLengthTag(tv.Input...)
+			if !computedResult.Equal(&tv.Expected) {
+				t.Errorf("Length-tagged hash result does not match.\nExpected: %s\nGot:      %s",
+					frToHex(tv.Expected), frToHex(computedResult))
 			}
+		})
+	}
+}
 
-			// Compare results
-			if !computedResult.Equal(&expectedResult) {
-				t.Errorf("Hash result does not match.\nExpected: %s\nGot:      %s",
-					frToHex(expectedResult), frToHex(computedResult))
+// TestPoseidon2MerkleKAT tests BuildMerkleTree roots and proofs against KAT vectors
+func TestPoseidon2MerkleKAT(t *testing.T) {
+	vectors := loadKATVectors(t)
+	
+	if len(vectors.Poseidon2TestVectors.MerkleTests) == 0 {
+		t.Fatal("No Merkle test vectors found")
+	}
+	
+	padded := false
+	for _, tv := range vectors.Poseidon2TestVectors.MerkleTests {
+		t.Run(tv.Description, func(t *testing.T) {
+			leaves, err := hexSliceToFrSlice(tv.Leaves)
+			if err != nil {
+				t.Fatalf("Failed to parse leaves: %v", err)
+			}
+			expectedRoot, err := hexToFr(tv.Root)
+			if err != nil {
+				t.Fatalf("Failed to parse expected root: %v", err)
+			}
+			
+			tree, err := BuildMerkleTree(leaves)
+			if err != nil {
+				t.Fatalf("BuildMerkleTree failed: %v", err)
+			}
+			root := tree.Root()
+			if !root.Equal(&expectedRoot) {
+				t.Errorf("Merkle root does not match.\nExpected: %s\nGot:      %s",
+					frToHex(expectedRoot), frToHex(root))
+			}
+			
+			for i, leaf := range leaves {
+				path, err := tree.Proof(i)
+				if err != nil {
+					t.Fatalf("Proof(%d) failed: %v", i, err)
+				}
+				if !VerifyMerkleProof(expectedRoot, leaf, i, path) {
+					t.Errorf("proof for leaf %d does not verify against the KAT root", i)
+				}
+			}
+			
+			if len(leaves)&(len(leaves)-1) != 0 {
+				padded = true
 			}
 		})
 	}
+	
+	if !padded {
+		t.Error("no Merkle vector has a non-power-of-two leaf count, so padding is untested")
+	}
 }
 
 // TestPoseidon2Compress2KAT tests the Compress2 function against KAT vectors
@@ -297,6 +438,269 @@ func TestKATIntegrity(t *testing.T) {
 	t.Logf("  BytesHash tests: %d", len(vectors.Poseidon2TestVectors.BytesHashTests))
 }
 
+// TestGenerateRandomKAT checks that seeded KAT generation is reproducible and self-consistent
+func TestGenerateRandomKAT(t *testing.T) {
+	const seed, count = 42, 8
+	
+	first := GenerateRandomKAT(seed, count)
+	second := GenerateRandomKAT(seed, count)
+	if !reflect.DeepEqual(first, second) {
+		t.Fatal("GenerateRandomKAT is not reproducible for the same seed")
+	}
+	
+	other := GenerateRandomKAT(seed+1, count)
+	if reflect.DeepEqual(first.PermutationTests, other.PermutationTests) {
+		t.Error("different seeds produced identical permutation vectors")
+	}
+	
+	if want := "0x30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001"; first.FieldModulus != want {
+		t.Errorf("field_modulus = %s, want r = %s", first.FieldModulus, want)
+	}
+	
+	if len(first.PermutationTests) != count || len(first.HashTests) != count ||
+		len(first.Compress2Tests) != count || len(first.BytesHashTests) != count {
+		t.Fatalf("expected %d vectors of each kind", count)
+	}
+	
+	// Every vector must round-trip through the implementation
+	for _, tv := range first.PermutationTests {
+		input, err := hexSliceToFrSlice(tv.Input)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		var state [T]Fr
+		copy(state[:], input)
+		ProductionPermutation(&state)
+		if got := frSliceToHexSlice(state[:]); !reflect.DeepEqual(got, tv.Expected) {
+			t.Errorf("%s: permutation mismatch", tv.Description)
+		}
+	}
+	for _, tv := range first.HashTests {
+		input, err := hexSliceToFrSlice(tv.Input)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		if got := frToHex(Hash(input...)); got != tv.Expected {
+			t.Errorf("%s: hash mismatch", tv.Description)
+		}
+	}
+	for _, tv := range first.Compress2Tests {
+		a, err := hexToFr(tv.A)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		b, err := hexToFr(tv.B)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		if got := frToHex(Compress2(a, b)); got != tv.Expected {
+			t.Errorf("%s: compress2 mismatch", tv.Description)
+		}
+	}
+	for _, tv := range first.BytesHashTests {
+		data, err := hex.DecodeString(tv.Data)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		digest, err := HashBytes(DomainGeneric, data)
+		if err != nil {
+			t.Fatalf("%s: %v", tv.Description, err)
+		}
+		if got := fmt.Sprintf("0x%x", digest); got != tv.Expected {
+			t.Errorf("%s: bytes hash mismatch", tv.Description)
+		}
+	}
+}
+
+// expectedParameterFingerprint locks the round constants and MDS matrix
+// Changing it means every previously stored digest is invalidated
+const expectedParameterFingerprint = "9d3df039dc814f0b12d928300e60314b25ae5405082c8e7a75e39f092c6c4df1"
+
+// TestParameterFingerprint guards against silent changes to constant generation
+func TestParameterFingerprint(t *testing.T) {
+	fp := ParameterFingerprint()
+	if got := hex.EncodeToString(fp[:]); got != expectedParameterFingerprint {
+		t.Errorf("parameter fingerprint changed.\nExpected: %s\nGot:      %s", expectedParameterFingerprint, got)
+	}
+	if ParameterFingerprint() != fp {
+		t.Error("ParameterFingerprint is not stable across calls")
+	}
+}
+
+// TestExportConstants checks the reference-format constant and matrix export
+func TestExportConstants(t *testing.T) {
+	constants := ExportRoundConstants()
+	if want := FULL_ROUNDS*T + PARTIAL_ROUNDS; len(constants) != want {
+		t.Fatalf("exported %d round constants, want %d", len(constants), want)
+	}
+	
+	// Walk the export in application order and compare against the tables
+	i := 0
+	for round := 0; round < TOTAL_ROUNDS; round++ {
+		width := 1
+		if round < FULL_ROUNDS/2 || round >= FULL_ROUNDS/2+PARTIAL_ROUNDS {
+			width = T
+		}
+		for pos := 0; pos < width; pos++ {
+			s := constants[i]
+			if len(s) != 66 || !strings.HasPrefix(s, "0x") {
+				t.Fatalf("constant %d %q is not canonical 0x-prefixed 32-byte hex", i, s)
+			}
+			c, err := hexToFr(s)
+			if err != nil {
+				t.Fatalf("constant %d is not valid hex: %v", i, err)
+			}
+			if !c.Equal(&roundConstants[round][pos]) {
+				t.Errorf("constant %d does not match round %d position %d", i, round, pos)
+			}
+			i++
+		}
+	}
+	
+	matrices := ExportMatrices()
+	if len(matrices) != T {
+		t.Fatalf("exported %d matrix rows, want %d", len(matrices), T)
+	}
+	for r, row := range matrices {
+		if len(row) != T {
+			t.Fatalf("matrix row %d has %d entries, want %d", r, len(row), T)
+		}
+		for c, s := range row {
+			m, err := hexToFr(s)
+			if err != nil {
+				t.Fatalf("matrix entry [%d][%d] is not valid hex: %v", r, c, err)
+			}
+			if !m.Equal(&mdsMatrix[r][c]) {
+				t.Errorf("matrix entry [%d][%d] does not match", r, c)
+			}
+		}
+	}
+}
+
+// TestCompareAgainstKATFile checks the bundled vector files and a tampered copy
+func TestCompareAgainstKATFile(t *testing.T) {
+	for _, path := range []string{"kat/kat.json", "kat/generated_kat.json"} {
+		mismatches, err := CompareAgainstKATFile(path)
+		if err != nil {
+			t.Fatalf("CompareAgainstKATFile(%s) failed: %v", path, err)
+		}
+		for _, m := range mismatches {
+			t.Errorf("%s: %s %q mismatch\nExpected: %s\nGot:      %s", path, m.Kind, m.Description, m.Expected, m.Got)
+		}
+	}
+	
+	// Corrupt one expected hash and check it is reported rather than failing
+	kat, err := GenerateKATVectors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kat.HashTests[1].Expected = frToHex(FromUint64(1))
+	data, err := json.Marshal(kat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := t.TempDir() + "/tampered.json"
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	
+	mismatches, err := CompareAgainstKATFile(path)
+	if err != nil {
+		t.Fatalf("CompareAgainstKATFile(tampered) failed: %v", err)
+	}
+	if len(mismatches) != 1 || mismatches[0].Kind != "hash" || mismatches[0].Description != kat.HashTests[1].Description {
+		t.Errorf("expected one hash mismatch, got %+v", mismatches)
+	}
+	
+	if _, err := CompareAgainstKATFile(t.TempDir() + "/missing.json"); err == nil {
+		t.Error("missing file should return an error")
+	}
+}
+
+// TestKATByteOrder checks vectors are accepted only under their own byte order
+func TestKATByteOrder(t *testing.T) {
+	if failures := checkPermutationKATOrder(t, "kat/kat.json", false); len(failures) != 0 {
+		t.Fatalf("big-endian vectors failed as big-endian: %v", failures)
+	}
+	
+	// Re-encode the same logical vectors little-endian
+	vectors := loadKATVectors(t)
+	for i, tv := range vectors.Poseidon2TestVectors.PermutationTests {
+		c, err := decodePermutationKAT(tv, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < T; j++ {
+			tv.Input[j] = frToHexLE(c.Input[j])
+			tv.Expected[j] = frToHexLE(c.Expected[j])
+		}
+		vectors.Poseidon2TestVectors.PermutationTests[i] = tv
+	}
+	data, err := json.Marshal(vectors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := t.TempDir() + "/kat_le.json"
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	
+	// Both files decode to the same logical vectors under their own byte order
+	be := loadKATVectorsLE(t, "kat/kat.json", false)
+	le := loadKATVectorsLE(t, path, true)
+	if !reflect.DeepEqual(be, le) {
+		t.Error("little-endian file does not decode to the big-endian vectors")
+	}
+	
+	if failures := checkPermutationKATOrder(t, path, true); len(failures) != 0 {
+		t.Errorf("little-endian vectors failed as little-endian: %v", failures)
+	}
+	
+	failures := checkPermutationKATOrder(t, path, false)
+	if len(failures) != len(vectors.Poseidon2TestVectors.PermutationTests) {
+		t.Fatalf("expected every little-endian vector to fail as big-endian, got %d failures", len(failures))
+	}
+	for _, msg := range failures {
+		if !strings.Contains(msg, "other byte order") {
+			t.Errorf("failure lacks a byte-order diagnostic: %s", msg)
+		}
+	}
+}
+
+// TestHexToFrLE checks little-endian decoding of short and odd-length hex strings
+func TestHexToFrLE(t *testing.T) {
+	cases := []struct {
+		in   string
+		want uint64
+	}{
+		{"0x1", 1},
+		{"0x01", 1},
+		{"0x0100", 1},
+		{"0x123", 0x2301}, // Read as 01 23, least significant byte first
+		{"0x0001", 0x100},
+		{"ff", 0xff},
+		{"0X1", 1},
+	}
+	for _, c := range cases {
+		got, err := hexToFrLE(c.in)
+		if err != nil {
+			t.Fatalf("hexToFrLE(%q): %v", c.in, err)
+		}
+		if want := FromUint64(c.want); !got.Equal(&want) {
+			t.Errorf("hexToFrLE(%q) = %s, want %d", c.in, got.String(), c.want)
+		}
+	}
+	
+	// A full-width encoding round-trips through frToHexLE
+	x := FromUint64(0x0102030405)
+	if got, err := hexToFrLE(frToHexLE(x)); err != nil || !got.Equal(&x) {
+		t.Errorf("round trip through frToHexLE failed: %v", err)
+	}
+	if _, err := hexToFrLE("0xzz"); err == nil {
+		t.Error("invalid hex should be rejected")
+	}
+}
+
 // BenchmarkKATTests provides performance baseline for KAT operations
 func BenchmarkPermutationKAT(b *testing.B) {
 	// Use the first permutation test vector
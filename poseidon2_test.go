@@ -1,10 +1,20 @@
 package poseidon2
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"math"
+	"math/big"
+	"math/rand"
 	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 // Test vector structure matching kat.json
@@ -147,34 +157,36 @@ func TestSBox(t *testing.T) {
 	}
 }
 
-// TestPermutation tests the Poseidon2 permutation
+// TestPermutation checks ProductionPermutation against the permutation vectors in kat/kat.json
 func TestPermutation(t *testing.T) {
 	vectors := loadTestVectors(t)
+	if len(vectors.Poseidon2TestVectors.PermutationTests) == 0 {
+		t.Fatal("kat/kat.json contains no permutation vectors")
+	}
 	
 	for _, test := range vectors.Poseidon2TestVectors.PermutationTests {
 		t.Run(test.Description, func(t *testing.T) {
-			// Convert input strings to Fr elements (simplified)
-			var state [T]Fr
-			for i, inputStr := range test.Input {
-				if i >= T {
-					break
-				}
-				// Simplified conversion - would implement proper hex parsing
-				if inputStr == "0x0" {
-					state[i] = Zero()
-				} else if inputStr == "0x1" {
-					state[i] = One()
-				} else {
-					state[i] = FromUint64(uint64(len(inputStr))) // Placeholder
-				}
+			input, err := hexSliceToFrSlice(test.Input)
+			if err != nil {
+				t.Fatalf("Failed to parse input: %v", err)
+			}
+			expected, err := hexSliceToFrSlice(test.Expected)
+			if err != nil {
+				t.Fatalf("Failed to parse expected state: %v", err)
+			}
+			if len(input) != T || len(expected) != T {
+				t.Fatalf("vector has %d inputs and %d outputs, want %d each", len(input), len(expected), T)
 			}
 			
-			// Apply permutation
+			var state [T]Fr
+			copy(state[:], input)
 			ProductionPermutation(&state)
 			
-			// Note: This is a basic structure test
-			// Real implementation would compare against actual expected values
-			t.Logf("Permutation result: state modified")
+			for i := 0; i < T; i++ {
+				if !state[i].Equal(&expected[i]) {
+					t.Errorf("state[%d] = %s, want %s", i, frToHex(state[i]), frToHex(expected[i]))
+				}
+			}
 		})
 	}
 }
@@ -233,6 +245,21 @@ func TestCompress2(t *testing.T) {
 	}
 }
 
+// TestCompress2Bytes checks the byte-returning compressor against Compress2
+func TestCompress2Bytes(t *testing.T) {
+	a, b := FromUint64(3), FromUint64(4)
+	got := Compress2Bytes(a, b)
+	if want := Compress2(a, b).ToBytes32(); got != want {
+		t.Error("Compress2Bytes differs from Compress2(a, b).ToBytes32()")
+	}
+	if again := Compress2Bytes(a, b); again != got {
+		t.Error("Compress2Bytes is not deterministic")
+	}
+	if swapped := Compress2Bytes(b, a); swapped == got {
+		t.Error("Compress2Bytes should depend on argument order")
+	}
+}
+
 // TestHashBytes tests the byte hashing interface
 func TestHashBytes(t *testing.T) {
 	// Test with empty data
@@ -274,6 +301,8 @@ func TestDomainSeparation(t *testing.T) {
 		DomainPolicyRoot,
 		DomainFSChallenge,
 		DomainTapTweak,
+		DomainMerkleLeaf,
+		DomainMerkleNode,
 	}
 	
 	results := make([][32]byte, len(domains))
@@ -302,6 +331,7 @@ func BenchmarkCompress2(b *testing.B) {
 	a := FromUint64(12345)
 	c := FromUint64(67890)
 	
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		Compress2(a, c)
@@ -423,7 +453,7 @@ func TestComplexityAnalysis(t *testing.T) {
 	for i := 0; i < 15; i++ { // Repeat 15 times (> 10)
 		patternData = append(patternData, pattern...)
 	}
-	patternComplexity := analyzePatterns(patternData)
+	patternComplexity := analyzePatterns(patternData, DefaultComplexityWeights())
 	if patternComplexity == 0 {
 		t.Error("Should detect repetitive patterns")
 	}
@@ -434,7 +464,7 @@ func TestComplexityAnalysis(t *testing.T) {
 		lowEntropyData[i] = byte(i % 3) // Only uses 3 different bytes
 	}
 	
-	entropyComplexity := analyzeEntropy(lowEntropyData)
+	entropyComplexity := analyzeEntropy(lowEntropyData, DefaultComplexityWeights())
 	if entropyComplexity == 0 {
 		t.Error("Should detect low entropy patterns")
 	}
@@ -449,8 +479,3493 @@ func TestComplexityAnalysis(t *testing.T) {
 		}
 	}
 	
-	edgeComplexity := analyzeEdgeCases(edgeCaseData)
+	edgeComplexity := analyzeEdgeCases(edgeCaseData, DefaultComplexityWeights())
 	if edgeComplexity == 0 {
 		t.Error("Should detect edge case byte patterns")
 	}
-}
\ No newline at end of file
+}
+// TestValidateInputLowEntropy ensures ordinary low-entropy data passes the default validator
+func TestValidateInputLowEntropy(t *testing.T) {
+	zeros := make([]byte, 10*1024)
+	if err := ValidateInput(zeros); err != nil {
+		t.Errorf("10KB all-zero buffer should pass ValidateInput: %v", err)
+	}
+	
+	key := []byte("0123456789abcdef")
+	var repeated []byte
+	for i := 0; i < 64; i++ {
+		repeated = append(repeated, key...)
+	}
+	if err := ValidateInput(repeated); err != nil {
+		t.Errorf("repeated-pattern buffer should pass ValidateInput: %v", err)
+	}
+	
+	// The heuristics remain available on request
+	if err := ValidateInputStrict(zeros); err == nil {
+		t.Error("ValidateInputStrict should flag a 10KB all-zero buffer")
+	}
+	if err := ValidateInputStrict([]byte("valid test data with reasonable entropy")); err != nil {
+		t.Errorf("Valid input should pass strict validation: %v", err)
+	}
+	if err := ValidateInputStrict(make([]byte, MaxInputSize+1)); err == nil {
+		t.Error("ValidateInputStrict should still enforce MaxInputSize")
+	}
+}
+
+// reverseBytes32 returns b with its byte order reversed
+func reverseBytes32(b [32]byte) [32]byte {
+	var r [32]byte
+	for i := range b {
+		r[i] = b[31-i]
+	}
+	return r
+}
+
+// TestLittleEndianBytes tests the little-endian byte interface
+func TestLittleEndianBytes(t *testing.T) {
+	values := []Fr{
+		Zero(),
+		One(),
+		FromUint64(12345),
+		FromUint64(0xFEDCBA0987654321),
+		Hash(FromUint64(1), FromUint64(2)),
+	}
+	
+	for i, v := range values {
+		// Round trip through little-endian bytes
+		le := v.ToBytesLE()
+		back := FromBytesLE(le)
+		if !back.Equal(&v) {
+			t.Errorf("value %d: FromBytesLE(ToBytesLE(x)) != x", i)
+		}
+		
+		// Little-endian is the exact byte reversal of big-endian
+		be := v.ToBytes32()
+		if le != reverseBytes32(be) {
+			t.Errorf("value %d: ToBytesLE is not the reverse of ToBytes32", i)
+		}
+		fromLE := FromBytesLE(reverseBytes32(be))
+		fromBE := FromBytes(be)
+		if !fromLE.Equal(&fromBE) {
+			t.Errorf("value %d: FromBytesLE(reverse(b)) != FromBytes(b)", i)
+		}
+	}
+	
+	// Least significant byte comes first
+	five := FromUint64(5)
+	le := five.ToBytesLE()
+	if le[0] != 5 || le[31] != 0 {
+		t.Errorf("ToBytesLE(5) has unexpected layout: %x", le)
+	}
+}
+
+// TestFrString tests the decimal and hex string representations
+func TestFrString(t *testing.T) {
+	if got := FromUint64(12345).String(); got != "12345" {
+		t.Errorf("FromUint64(12345).String() = %q, want \"12345\"", got)
+	}
+	if got := Zero().String(); got != "0" {
+		t.Errorf("Zero().String() = %q, want \"0\"", got)
+	}
+	if got := fmt.Sprint(FromUint64(5)); got != "5" {
+		t.Errorf("fmt.Sprint(FromUint64(5)) = %q, want \"5\"", got)
+	}
+	
+	var minusOne Fr
+	one := One()
+	minusOne.Neg(&one)
+	want := "21888242871839275222246405745257275088548364400416034343698204186575808495616"
+	if got := minusOne.String(); got != want {
+		t.Errorf("(-1).String() = %q, want %q", got, want)
+	}
+	
+	x := FromUint64(0xFEDCBA0987654321)
+	if x.Hex() != frToHex(x) {
+		t.Errorf("Hex() = %s, frToHex = %s", x.Hex(), frToHex(x))
+	}
+	if got := FromUint64(255).Hex(); got != "0x00000000000000000000000000000000000000000000000000000000000000ff" {
+		t.Errorf("FromUint64(255).Hex() = %s", got)
+	}
+}
+
+// TestHashFixedArity checks Hash1/Hash2/Hash3 against the variadic Hash
+func TestHashFixedArity(t *testing.T) {
+	a := FromUint64(1)
+	b := FromUint64(2)
+	c := FromUint64(3)
+	
+	if got, want := Hash1(a), Hash(a); !got.Equal(&want) {
+		t.Error("Hash1(a) != Hash(a)")
+	}
+	if got, want := Hash2(a, b), Hash(a, b); !got.Equal(&want) {
+		t.Error("Hash2(a, b) != Hash(a, b)")
+	}
+	if got, want := Hash3(a, b, c), Hash(a, b, c); !got.Equal(&want) {
+		t.Error("Hash3(a, b, c) != Hash(a, b, c)")
+	}
+	
+	allocs := testing.AllocsPerRun(10, func() {
+		Hash1(a)
+		Hash2(a, b)
+		Hash3(a, b, c)
+	})
+	if allocs != 0 {
+		t.Errorf("fixed-arity hashes allocated %.1f times per run, want 0", allocs)
+	}
+}
+
+// BenchmarkHash2 benchmarks the fixed-arity pair hash
+func BenchmarkHash2(b *testing.B) {
+	x := FromUint64(12345)
+	y := FromUint64(67890)
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Hash2(x, y)
+	}
+}
+
+// BenchmarkHashVariadic2 benchmarks the variadic hash on a pair for comparison
+func BenchmarkHashVariadic2(b *testing.B) {
+	x := FromUint64(12345)
+	y := FromUint64(67890)
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Hash(x, y)
+	}
+}
+
+// TestFromUint64Checked sweeps uint64 edge cases against a big.Int reference
+func TestFromUint64Checked(t *testing.T) {
+	values := []uint64{0, 1, 2, 3, 255, 12345, 0x123456789ABCDEF0, 0xFEDCBA0987654321, ^uint64(0) - 1, ^uint64(0)}
+	for shift := uint(0); shift < 64; shift++ {
+		values = append(values, uint64(1)<<shift, (uint64(1)<<shift)-1)
+	}
+	
+	for _, x := range values {
+		checked, err := FromUint64Checked(x)
+		if err != nil {
+			t.Fatalf("FromUint64Checked(%d): %v", x, err)
+		}
+		plain := FromUint64(x)
+		if !checked.Equal(&plain) {
+			t.Errorf("FromUint64Checked(%d) != FromUint64(%d)", x, x)
+		}
+		if got := plain.ToBigInt(); got.Cmp(new(big.Int).SetUint64(x)) != 0 {
+			t.Errorf("FromUint64(%d) round-trips to %s", x, got)
+		}
+	}
+}
+
+// TestHashBytesCT checks HashBytesCT matches HashBytes and takes a content-independent path
+func TestHashBytesCT(t *testing.T) {
+	for _, n := range []int{0, 1, 30, 31, 32, 62, 100} {
+		zeros := make([]byte, n)
+		ones := make([]byte, n)
+		for i := range ones {
+			ones[i] = 0xFF
+		}
+		
+		for _, data := range [][]byte{zeros, ones} {
+			want, err := HashBytes(DomainGeneric, data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := HashBytesCT(DomainGeneric, data); got != want {
+				t.Errorf("HashBytesCT differs from HashBytes for %d bytes", n)
+			}
+		}
+		
+		// Same length, different content: same chunk count and same sponge position
+		h1, h2 := NewHasher(), NewHasher()
+		c1 := absorbBytesCT(h1, zeros)
+		c2 := absorbBytesCT(h2, ones)
+		if c1 != c2 || c1 != (n+30)/31 {
+			t.Errorf("%d bytes: chunk counts %d and %d, want %d", n, c1, c2, (n+30)/31)
+		}
+		if h1.absorbed != h2.absorbed {
+			t.Errorf("%d bytes: sponge positions diverged (%d vs %d)", n, h1.absorbed, h2.absorbed)
+		}
+	}
+}
+
+// TestMAC tests the keyed capacity MAC
+func TestMAC(t *testing.T) {
+	key1 := FromUint64(0xA11CE)
+	key2 := FromUint64(0xB0B)
+	msg := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	
+	tag1 := MAC(key1, msg...)
+	tag1Again := MAC(key1, msg...)
+	if !tag1.Equal(&tag1Again) {
+		t.Error("MAC is not deterministic")
+	}
+	
+	tag2 := MAC(key2, msg...)
+	if tag1.Equal(&tag2) {
+		t.Error("different keys produced the same tag")
+	}
+	
+	// The tag must depend on the key even for an empty message
+	empty1 := MAC(key1)
+	empty2 := MAC(key2)
+	if empty1.Equal(&empty2) {
+		t.Error("different keys produced the same tag for an empty message")
+	}
+	
+	// A keyed tag must differ from the unkeyed hash and from the key itself
+	plain := Hash(msg...)
+	if tag1.Equal(&plain) {
+		t.Error("MAC equals unkeyed Hash")
+	}
+	if tag1.Equal(&key1) {
+		t.Error("MAC leaked the key")
+	}
+	
+	// A zero key reduces to the plain sponge
+	zeroKeyed := MAC(Zero(), msg...)
+	if !zeroKeyed.Equal(&plain) {
+		t.Error("MAC with zero key should equal Hash")
+	}
+}
+
+// TestVerifyMAC checks tag verification accepts the real tag and rejects near misses
+func TestVerifyMAC(t *testing.T) {
+	key := FromUint64(0xA11CE)
+	msg := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	tag := MAC(key, msg...)
+	
+	if !VerifyMAC(key, tag, msg...) {
+		t.Fatal("correct tag did not verify")
+	}
+	if !VerifyMAC(key, MAC(key), []Fr{}...) {
+		t.Error("correct tag for an empty message did not verify")
+	}
+	
+	for limb := 0; limb < 4; limb++ {
+		forged := tag
+		forged[limb] ^= 1
+		if VerifyMAC(key, forged, msg...) {
+			t.Errorf("tag with limb %d off by one bit verified", limb)
+		}
+	}
+	
+	if VerifyMAC(FromUint64(0xB0B), tag, msg...) {
+		t.Error("tag verified under the wrong key")
+	}
+	if VerifyMAC(key, tag, msg[:2]...) {
+		t.Error("tag verified for a different message")
+	}
+}
+
+// TestParseField tests decimal and hex parsing of field elements
+func TestParseField(t *testing.T) {
+	five := FromUint64(5)
+	for _, s := range []string{"5", "0x5", "0X05", "0x0000000000000005"} {
+		got, err := ParseField(s)
+		if err != nil {
+			t.Fatalf("ParseField(%q) failed: %v", s, err)
+		}
+		if !got.Equal(&five) {
+			t.Errorf("ParseField(%q) = %s, want 5", s, got)
+		}
+	}
+	
+	// The modulus itself reduces to zero
+	modulus := "21888242871839275222246405745257275088548364400416034343698204186575808495617"
+	got, err := ParseField(modulus)
+	if err != nil {
+		t.Fatalf("ParseField(modulus) failed: %v", err)
+	}
+	if !got.IsZero() {
+		t.Errorf("ParseField(modulus) = %s, want 0", got)
+	}
+	
+	// Values above the modulus wrap around
+	got, err = ParseField("21888242871839275222246405745257275088548364400416034343698204186575808495622")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(&five) {
+		t.Errorf("ParseField(r+5) = %s, want 5", got)
+	}
+	
+	// Negative input is negated in the field
+	got, err = ParseField("-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var minusOne Fr
+	one := One()
+	minusOne.Neg(&one)
+	if !got.Equal(&minusOne) {
+		t.Errorf("ParseField(\"-1\") = %s, want r-1", got)
+	}
+	
+	for _, s := range []string{"", "0x", "abc", "0xzz", "12.5", "1e10", "--1", "0x-5", "+5"} {
+		if _, err := ParseField(s); err == nil {
+			t.Errorf("ParseField(%q) should fail", s)
+		}
+	}
+}
+
+// TestDomainRegistry tests custom domain registration and lookup
+func TestDomainRegistry(t *testing.T) {
+	// Built-in domains are pre-registered
+	if tag, ok := LookupDomain("generic"); !ok || tag != DomainGeneric {
+		t.Errorf("LookupDomain(\"generic\") = 0x%x, %v", uint64(tag), ok)
+	}
+	
+	// Colliding with a built-in tag fails
+	if err := RegisterDomain("my-generic", DomainGeneric); err == nil {
+		t.Error("registering a built-in tag should fail")
+	}
+	
+	const custom Domain = 0x54455354 // "TEST"
+	if err := RegisterDomain("test-registry-custom", custom); err != nil {
+		t.Fatalf("RegisterDomain failed: %v", err)
+	}
+	t.Cleanup(func() {
+		domainRegistry.Lock()
+		delete(domainRegistry.byName, "test-registry-custom")
+		delete(domainRegistry.byTag, custom)
+		domainRegistry.Unlock()
+	})
+	if tag, ok := LookupDomain("test-registry-custom"); !ok || tag != custom {
+		t.Errorf("LookupDomain returned 0x%x, %v", uint64(tag), ok)
+	}
+	
+	// Re-using either the tag or the name fails
+	if err := RegisterDomain("test-registry-other", custom); err == nil {
+		t.Error("registering a colliding tag should fail")
+	}
+	if err := RegisterDomain("test-registry-custom", custom+1); err == nil {
+		t.Error("registering a duplicate name should fail")
+	}
+	
+	if _, ok := LookupDomain("test-registry-missing"); ok {
+		t.Error("LookupDomain should miss unregistered names")
+	}
+}
+
+// randomFr returns a field element from 256 random bits reduced mod r
+func randomFr(rng *rand.Rand) Fr {
+	var data [32]byte
+	rng.Read(data[:])
+	return FromBytes(data)
+}
+
+// TestExportedSBox checks SBox against Exp(x, 5) and the known small values
+func TestExportedSBox(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	five := big.NewInt(5)
+	
+	for i := 0; i < 100; i++ {
+		x := randomFr(rng)
+		var want Fr
+		want.Exp(&x, five)
+		got := SBox(x)
+		if !got.Equal(&want) {
+			t.Fatalf("SBox(%s) != %s^5", x, x)
+		}
+		internal := sBoxProd(&x)
+		if !internal.Equal(&got) {
+			t.Fatalf("sBoxProd and SBox disagree on %s", x)
+		}
+	}
+	
+	for _, tc := range []struct{ in, out uint64 }{{0, 0}, {1, 1}, {2, 32}} {
+		want := FromUint64(tc.out)
+		if got := SBox(FromUint64(tc.in)); !got.Equal(&want) {
+			t.Errorf("SBox(%d) = %s, want %d", tc.in, got, tc.out)
+		}
+	}
+}
+
+// TestExp tests field exponentiation against big.Int
+func TestExp(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	modulus := limbsToBigInt(&rModulus)
+	
+	for i := 0; i < 20; i++ {
+		x := randomFr(rng)
+		e := new(big.Int).Rand(rng, modulus)
+		
+		var got Fr
+		got.Exp(&x, e)
+		want := new(big.Int).Exp(x.ToBigInt(), e, modulus)
+		if got.ToBigInt().Cmp(want) != 0 {
+			t.Fatalf("Exp(%s, %s) = %s, want %s", x, e, got, want)
+		}
+	}
+	
+	// x^0 == 1 including for zero
+	var got Fr
+	zero := Zero()
+	got.Exp(&zero, big.NewInt(0))
+	one := One()
+	if !got.Equal(&one) {
+		t.Error("0^0 should be 1")
+	}
+}
+
+// TestSelfTest checks SelfTest passes and detects a corrupted constant
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("SelfTest failed on correct constants: %v", err)
+	}
+	
+	saved := montgomeryR2
+	defer func() { montgomeryR2 = saved }()
+	
+	montgomeryR2[1] ^= 1
+	if err := SelfTest(); err == nil {
+		t.Error("SelfTest should fail with a corrupted montgomeryR2")
+	}
+	montgomeryR2 = saved
+	
+	savedR := montgomeryR
+	defer func() { montgomeryR = savedR }()
+	montgomeryR[0]++
+	if err := SelfTest(); err == nil {
+		t.Error("SelfTest should fail with a corrupted montgomeryR")
+	}
+}
+
+// TestToBytes32Batch checks batch conversion against element-wise ToBytes32
+func TestToBytes32Batch(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	elements := []Fr{Zero(), One()}
+	for i := 0; i < 50; i++ {
+		elements = append(elements, randomFr(rng))
+	}
+	
+	batch := ToBytes32Batch(elements)
+	if len(batch) != len(elements) {
+		t.Fatalf("got %d outputs, want %d", len(batch), len(elements))
+	}
+	for i, e := range elements {
+		if batch[i] != e.ToBytes32() {
+			t.Errorf("element %d: batch output differs from ToBytes32", i)
+		}
+	}
+	
+	if got := ToBytes32Batch(nil); len(got) != 0 {
+		t.Errorf("ToBytes32Batch(nil) returned %d elements", len(got))
+	}
+}
+
+// benchmarkBytesElements returns 1024 deterministic elements for conversion benchmarks
+func benchmarkBytesElements() []Fr {
+	elements := make([]Fr, 1024)
+	for i := range elements {
+		elements[i] = FromUint64(uint64(i) * 0x9e3779b97f4a7c15)
+	}
+	return elements
+}
+
+// BenchmarkToBytes32Batch benchmarks batch conversion of 1024 elements
+func BenchmarkToBytes32Batch(b *testing.B) {
+	elements := benchmarkBytesElements()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ToBytes32Batch(elements)
+	}
+}
+
+// BenchmarkToBytes32Loop benchmarks converting 1024 elements one at a time
+func BenchmarkToBytes32Loop(b *testing.B) {
+	elements := benchmarkBytesElements()
+	out := make([][32]byte, len(elements))
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for j := range elements {
+			out[j] = elements[j].ToBytes32()
+		}
+	}
+}
+
+// TestHashWithIV tests capacity IV seeding
+func TestHashWithIV(t *testing.T) {
+	inputs := [][]Fr{
+		{},
+		{FromUint64(1)},
+		{FromUint64(1), FromUint64(2)},
+		{FromUint64(1), FromUint64(2), FromUint64(3)},
+	}
+	
+	for _, in := range inputs {
+		plain := Hash(in...)
+		if got := HashWithIV(Zero(), in...); !got.Equal(&plain) {
+			t.Errorf("zero IV differs from Hash for %d elements", len(in))
+		}
+		if len(in) == 0 {
+			continue
+		}
+		
+		iv1 := HashWithIV(FromUint64(1), in...)
+		iv2 := HashWithIV(FromUint64(2), in...)
+		if iv1.Equal(&iv2) || iv1.Equal(&plain) {
+			t.Errorf("distinct IVs collided for %d elements", len(in))
+		}
+	}
+}
+
+// TestProductionPermutationTrace checks the round trace against the plain permutation
+func TestProductionPermutationTrace(t *testing.T) {
+	input := [T]Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	
+	expected := input
+	ProductionPermutation(&expected)
+	
+	traced := input
+	trace := ProductionPermutationTrace(&traced)
+	if len(trace) != TOTAL_ROUNDS {
+		t.Fatalf("got %d snapshots, want %d", len(trace), TOTAL_ROUNDS)
+	}
+	if trace[len(trace)-1] != expected {
+		t.Error("final snapshot differs from ProductionPermutation")
+	}
+	if traced != expected {
+		t.Error("traced state differs from ProductionPermutation")
+	}
+	
+	// The first snapshot is one full round of the input
+	first := input
+	fullRound(&first, 0)
+	if trace[0] != first {
+		t.Error("first snapshot is not the state after round 0")
+	}
+}
+
+// TestShifts compares Lsh/Rsh against big.Int shifts reduced mod r
+func TestShifts(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	modulus := limbsToBigInt(&rModulus)
+	
+	var minusOne Fr
+	one := One()
+	minusOne.Neg(&one)
+	values := []Fr{Zero(), One(), minusOne, FromUint64(0xFEDCBA0987654321)}
+	for i := 0; i < 20; i++ {
+		values = append(values, randomFr(rng))
+	}
+	
+	for _, x := range values {
+		for _, n := range []uint{0, 1, 2, 7, 63, 64, 65, 128, 200, 253, 254, 255, 256, 300} {
+			var got Fr
+			got.Lsh(&x, n)
+			want := new(big.Int).Lsh(x.ToBigInt(), n)
+			want.Mod(want, modulus)
+			if got.ToBigInt().Cmp(want) != 0 {
+				t.Fatalf("Lsh(%s, %d) = %s, want %s", x, n, got, want)
+			}
+			
+			got.Rsh(&x, n)
+			want = new(big.Int).Rsh(x.ToBigInt(), n)
+			if got.ToBigInt().Cmp(want) != 0 {
+				t.Fatalf("Rsh(%s, %d) = %s, want %s", x, n, got, want)
+			}
+		}
+	}
+	
+	// In-place use works
+	x := FromUint64(5)
+	x.Lsh(&x, 3)
+	forty := FromUint64(40)
+	if !x.Equal(&forty) {
+		t.Errorf("5 << 3 = %s, want 40", x)
+	}
+	x.Rsh(&x, 2)
+	ten := FromUint64(10)
+	if !x.Equal(&ten) {
+		t.Errorf("40 >> 2 = %s, want 10", x)
+	}
+}
+
+// TestFromBytesN tests variable-length big-endian conversion
+func TestFromBytesN(t *testing.T) {
+	got, err := FromBytesN(nil)
+	if err != nil || !got.IsZero() {
+		t.Errorf("FromBytesN(empty) = %s, %v; want 0", got, err)
+	}
+	
+	got, err = FromBytesN([]byte{0x2a})
+	want := FromUint64(42)
+	if err != nil || !got.Equal(&want) {
+		t.Errorf("FromBytesN([0x2a]) = %s, %v; want 42", got, err)
+	}
+	
+	b31 := make([]byte, 31)
+	for i := range b31 {
+		b31[i] = byte(i + 1)
+	}
+	got, err = FromBytesN(b31)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ToBigInt().Cmp(new(big.Int).SetBytes(b31)) != 0 {
+		t.Errorf("FromBytesN(31 bytes) = %s", got)
+	}
+	
+	// 32 bytes of 0xFF exceed r and must be reduced
+	b32 := make([]byte, 32)
+	for i := range b32 {
+		b32[i] = 0xFF
+	}
+	got, err = FromBytesN(b32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var arr [32]byte
+	copy(arr[:], b32)
+	if want := FromBytes(arr); !got.Equal(&want) {
+		t.Error("FromBytesN(32 bytes) differs from FromBytes")
+	}
+	
+	if _, err := FromBytesN(make([]byte, 33)); err == nil {
+		t.Error("FromBytesN should reject 33 bytes")
+	}
+}
+
+// TestHashUint64 checks HashUint64 against Hash over converted elements
+func TestHashUint64(t *testing.T) {
+	got := HashUint64(1, 2)
+	want := Hash(FromUint64(1), FromUint64(2))
+	if !got.Equal(&want) {
+		t.Error("HashUint64(1, 2) != Hash(FromUint64(1), FromUint64(2))")
+	}
+	
+	values := []uint64{0, 7, ^uint64(0), 12345, 42}
+	elements := make([]Fr, len(values))
+	for i, v := range values {
+		elements[i] = FromUint64(v)
+	}
+	got = HashUint64(values...)
+	want = Hash(elements...)
+	if !got.Equal(&want) {
+		t.Error("HashUint64 differs from Hash for mixed values")
+	}
+	
+	got = HashUint64()
+	want = Hash()
+	if !got.Equal(&want) {
+		t.Error("HashUint64() != Hash()")
+	}
+}
+
+// TestWideHash tests the two-element output hash
+func TestWideHash(t *testing.T) {
+	inputs := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	
+	first, second := WideHash(inputs...)
+	plain := Hash(inputs...)
+	if !first.Equal(&plain) {
+		t.Error("first WideHash output should equal Hash")
+	}
+	if first.Equal(&second) {
+		t.Error("WideHash outputs should differ")
+	}
+	
+	again1, again2 := WideHash(inputs...)
+	if !again1.Equal(&first) || !again2.Equal(&second) {
+		t.Error("WideHash is not deterministic")
+	}
+}
+
+// TestSqueezeN tests multi-block squeezing
+func TestSqueezeN(t *testing.T) {
+	h := NewHasher()
+	h.Absorb(FromUint64(7))
+	out := h.SqueezeN(5)
+	
+	// Successive calls continue the same stream
+	h2 := NewHasher()
+	h2.Absorb(FromUint64(7))
+	split := append(h2.SqueezeN(3), h2.SqueezeN(2)...)
+	for i := range out {
+		if !out[i].Equal(&split[i]) {
+			t.Errorf("squeeze stream diverges at element %d", i)
+		}
+	}
+	
+	// Elements 0 and 1 come from one block, element 2 from the next permutation
+	state := [T]Fr{FromUint64(7), Zero(), Zero()}
+	ProductionPermutation(&state)
+	if !out[0].Equal(&state[0]) || !out[1].Equal(&state[1]) {
+		t.Error("first block does not match the permuted state")
+	}
+	ProductionPermutation(&state)
+	if !out[2].Equal(&state[0]) {
+		t.Error("second block does not follow a permutation")
+	}
+}
+
+// TestHasherRates compares the default sponge with separately configured absorb and squeeze rates
+func TestHasherRates(t *testing.T) {
+	inputs := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	
+	def := NewHasher()
+	def.AbsorbMany(inputs)
+	want := def.SqueezeN(4)
+	
+	explicit, err := NewHasherRates(2, 2)
+	if err != nil {
+		t.Fatalf("NewHasherRates(2, 2) failed: %v", err)
+	}
+	explicit.AbsorbMany(inputs)
+	if !EqualSlices(explicit.SqueezeN(4), want) {
+		t.Error("NewHasherRates(2, 2) differs from NewHasher")
+	}
+	
+	// squeezeRate=1 outputs state[0] of successive permutations
+	narrow, err := NewHasherRates(2, 1)
+	if err != nil {
+		t.Fatalf("NewHasherRates(2, 1) failed: %v", err)
+	}
+	narrow.AbsorbMany(inputs)
+	got := narrow.SqueezeN(3)
+	
+	state := [T]Fr{FromUint64(1), FromUint64(2), Zero()}
+	ProductionPermutation(&state)
+	state[0].Add(&state[0], &inputs[2])
+	for i := range got {
+		ProductionPermutation(&state)
+		if !got[i].Equal(&state[0]) {
+			t.Errorf("squeezeRate=1 element %d does not match state[0] of permutation %d", i, i+1)
+		}
+	}
+	if !got[0].Equal(&want[0]) {
+		t.Error("first squeezed element should not depend on the squeeze rate")
+	}
+	if got[1].Equal(&want[1]) {
+		t.Error("squeezeRate=1 should not output the second rate element")
+	}
+	
+	// Reset keeps the configured rates
+	narrow.Reset()
+	narrow.AbsorbMany(inputs)
+	if !EqualSlices(narrow.SqueezeN(3), got) {
+		t.Error("Reset changed the squeeze rate")
+	}
+	
+	// absorbRate=1 permutes after every element
+	single, err := NewHasherRates(1, 2)
+	if err != nil {
+		t.Fatalf("NewHasherRates(1, 2) failed: %v", err)
+	}
+	single.AbsorbMany(inputs[:1])
+	state = [T]Fr{inputs[0], Zero(), Zero()}
+	ProductionPermutation(&state)
+	if got := single.Finalize(); !got.Equal(&state[0]) {
+		t.Error("absorbRate=1 did not permute after a single element")
+	}
+	
+	for _, rates := range [][2]int{{0, 2}, {2, 0}, {T, 2}, {2, T}, {-1, 1}} {
+		if _, err := NewHasherRates(rates[0], rates[1]); err == nil {
+			t.Errorf("NewHasherRates(%d, %d) should fail", rates[0], rates[1])
+		}
+	}
+}
+
+// TestReduceWide compares wide reduction against big.Int mod r
+func TestReduceWide(t *testing.T) {
+	rng := rand.New(rand.NewSource(6))
+	modulus := limbsToBigInt(&rModulus)
+	
+	for _, n := range []int{0, 1, 32, 48, 64, 100} {
+		for i := 0; i < 10; i++ {
+			b := make([]byte, n)
+			rng.Read(b)
+			if i == 0 {
+				for j := range b {
+					b[j] = 0xFF
+				}
+			}
+			
+			got := ReduceWide(b)
+			want := new(big.Int).Mod(new(big.Int).SetBytes(b), modulus)
+			if got.ToBigInt().Cmp(want) != 0 {
+				t.Fatalf("ReduceWide(%d bytes) = %s, want %s", n, got, want)
+			}
+		}
+	}
+}
+
+// TestHashPairDomain tests ordering and domain separation of HashPairDomain
+func TestHashPairDomain(t *testing.T) {
+	left := FromUint64(1).ToBytes32()
+	right := FromUint64(2).ToBytes32()
+	
+	lr := HashPairDomain(DomainGeneric, left, right)
+	rl := HashPairDomain(DomainGeneric, right, left)
+	if lr == rl {
+		t.Error("HashPairDomain should depend on left/right order")
+	}
+	
+	other := HashPairDomain(DomainPOETNode, left, right)
+	if lr == other {
+		t.Error("distinct domains produced the same digest")
+	}
+	if lr == HashPair(left, right) {
+		t.Error("HashPairDomain should differ from untagged HashPair")
+	}
+	if lr != HashPairDomain(DomainGeneric, left, right) {
+		t.Error("HashPairDomain is not deterministic")
+	}
+}
+
+// TestEqualSlicesAndFrKey tests slice comparison and map keys
+func TestEqualSlicesAndFrKey(t *testing.T) {
+	a := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	b := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	
+	if !EqualSlices(a, b) {
+		t.Error("equal slices reported unequal")
+	}
+	if EqualSlices(a, b[:2]) {
+		t.Error("slices of different length reported equal")
+	}
+	if !EqualSlices(nil, []Fr{}) {
+		t.Error("nil and empty slices should be equal")
+	}
+	b[2] = FromUint64(4)
+	if EqualSlices(a, b) {
+		t.Error("different slices reported equal")
+	}
+	
+	// x + r in raw limbs is a non-canonical representation of x
+	x := FromUint64(12345)
+	var nonCanonical Fr
+	nonCanonical.add(&x, &rModulus)
+	if nonCanonical.Equal(&x) {
+		t.Fatal("test setup: representations should differ in limbs")
+	}
+	if FrKey(nonCanonical) != FrKey(x) {
+		t.Error("FrKey differs across representations of the same value")
+	}
+	if !EqualSlices([]Fr{x}, []Fr{nonCanonical}) {
+		t.Error("EqualSlices should compare canonical values")
+	}
+	if FrKey(x) == FrKey(FromUint64(12346)) {
+		t.Error("FrKey collided for distinct values")
+	}
+	
+	// Keys deduplicate leaves in a set
+	set := make(map[string]Fr)
+	for _, e := range []Fr{x, FromUint64(1), x, nonCanonical} {
+		set[FrKey(e)] = e
+	}
+	if len(set) != 2 {
+		t.Errorf("set holds %d elements, want 2", len(set))
+	}
+}
+
+// TestPoseidon2Rand tests reproducibility and divergence of the counter-mode generator
+func TestPoseidon2Rand(t *testing.T) {
+	r1 := NewPoseidon2Rand(FromUint64(1))
+	r2 := NewPoseidon2Rand(FromUint64(1))
+	r3 := NewPoseidon2Rand(FromUint64(2))
+	
+	seen := make(map[string]bool)
+	for i := 0; i < 16; i++ {
+		a, b, c := r1.NextFr(), r2.NextFr(), r3.NextFr()
+		if !a.Equal(&b) {
+			t.Fatalf("same seed diverged at element %d", i)
+		}
+		if a.Equal(&c) {
+			t.Fatalf("different seeds agreed at element %d", i)
+		}
+		if seen[FrKey(a)] {
+			t.Fatalf("stream repeated an element at %d", i)
+		}
+		seen[FrKey(a)] = true
+	}
+	
+	// Read is reproducible regardless of how the output is split
+	whole := make([]byte, 100)
+	NewPoseidon2Rand(FromUint64(9)).Read(whole)
+	
+	split := make([]byte, 100)
+	r := NewPoseidon2Rand(FromUint64(9))
+	for i := 0; i < 100; i += 7 {
+		end := i + 7
+		if end > 100 {
+			end = 100
+		}
+		if n, err := r.Read(split[i:end]); err != nil || n != end-i {
+			t.Fatalf("Read returned %d, %v", n, err)
+		}
+	}
+	if string(whole) != string(split) {
+		t.Error("Read output depends on buffer sizes")
+	}
+	
+	// Generator output differs from plain compression of the same inputs
+	first := NewPoseidon2Rand(FromUint64(1)).NextFr()
+	plain := Compress2(FromUint64(1), Zero())
+	if first.Equal(&plain) {
+		t.Error("generator is not domain separated from Compress2")
+	}
+}
+
+// TestValidateSecurityParameters tests the round-number lower bounds
+func TestValidateSecurityParameters(t *testing.T) {
+	// This package's configuration
+	if err := ValidateSecurityParameters(T, D, F, P, 254); err != nil {
+		t.Errorf("production parameters rejected: %v", err)
+	}
+	
+	// Published Poseidon2 instances over BN254
+	for _, tc := range []struct{ t, d, rF, rP int }{
+		{2, 5, 8, 56},
+		{4, 5, 8, 56},
+		{8, 5, 8, 57},
+	} {
+		if err := ValidateSecurityParameters(tc.t, tc.d, tc.rF, tc.rP, 254); err != nil {
+			t.Errorf("secure parameters %+v rejected: %v", tc, err)
+		}
+	}
+	
+	// Too few partial rounds, too few full rounds, and malformed parameters
+	for _, tc := range []struct{ t, d, rF, rP int }{
+		{3, 5, 8, 20},
+		{3, 5, 4, 56},
+		{3, 5, 2, 0},
+		{3, 4, 8, 56},
+		{1, 5, 8, 56},
+		{3, 5, 7, 56},
+		{3, 5, 8, -1},
+	} {
+		if err := ValidateSecurityParameters(tc.t, tc.d, tc.rF, tc.rP, 254); err == nil {
+			t.Errorf("insecure parameters %+v accepted", tc)
+		}
+	}
+}
+
+// isCanonical reports whether the raw limbs of f are below r
+func isCanonical(f *Fr) bool {
+	return limbsToBigInt(f).Cmp(limbsToBigInt(&rModulus)) < 0
+}
+
+// TestNegCanonical checks Neg always returns canonical elements
+func TestNegCanonical(t *testing.T) {
+	if debugChecks {
+		t.Skip("feeds non-canonical limbs to Add, which the poseidon2_debug build rejects")
+	}
+	modulus := limbsToBigInt(&rModulus)
+	rMinusOne := FromBigInt(new(big.Int).Sub(modulus, big.NewInt(1)))
+	rMinusTwo := FromBigInt(new(big.Int).Sub(modulus, big.NewInt(2)))
+	
+	// Raw limbs at and just below r, interpreted as Montgomery values
+	rawRMinusOne := rModulus
+	rawRMinusOne[0]--
+	
+	for i, x := range []Fr{Zero(), One(), rMinusOne, rMinusTwo, rawRMinusOne, rModulus} {
+		var neg Fr
+		neg.Neg(&x)
+		if !isCanonical(&neg) {
+			t.Errorf("case %d: Neg returned non-canonical limbs", i)
+		}
+		
+		var sum Fr
+		sum.Add(&x, &neg)
+		if !sum.IsZero() {
+			t.Errorf("case %d: x + (-x) != 0", i)
+		}
+	}
+	
+	// Raw r is a non-canonical zero and must negate to zero
+	var negR Fr
+	r := rModulus
+	negR.Neg(&r)
+	if !negR.IsZero() {
+		t.Error("Neg(r) should be zero")
+	}
+	
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 1000; i++ {
+		a := randomFr(rng)
+		var neg, sum Fr
+		neg.Neg(&a)
+		sum.Add(&a, &neg)
+		if !sum.IsZero() || !isCanonical(&neg) {
+			t.Fatalf("a + (-a) != 0 for a = %s", a)
+		}
+	}
+}
+
+// samplePolicy is a record hashed through FieldEncoder in tests
+type samplePolicy struct {
+	ID      uint64
+	Version uint64
+	Owner   Fr
+}
+
+func (p samplePolicy) EncodeFields() []Fr {
+	return append(Uint64Fields(p.ID, p.Version), p.Owner)
+}
+
+// TestHashStruct tests record hashing through FieldEncoder
+func TestHashStruct(t *testing.T) {
+	p := samplePolicy{ID: 1, Version: 2, Owner: FromUint64(3)}
+	
+	got := HashStruct(DomainPolicyRoot, p)
+	want := HashMany(DomainPolicyRoot, FromUint64(1), FromUint64(2), FromUint64(3))
+	if !got.Equal(&want) {
+		t.Error("HashStruct differs from HashMany over the encoded fields")
+	}
+	
+	// Swapping field values changes the digest
+	swapped := HashStruct(DomainPolicyRoot, samplePolicy{ID: 2, Version: 1, Owner: FromUint64(3)})
+	if got.Equal(&swapped) {
+		t.Error("HashStruct should be order-sensitive")
+	}
+	
+	other := HashStruct(DomainPOETNode, p)
+	if got.Equal(&other) {
+		t.Error("HashStruct should be domain separated")
+	}
+	
+	list := FieldList{FromUint64(1), FromUint64(2), FromUint64(3)}
+	if fromList := HashStruct(DomainPolicyRoot, list); !fromList.Equal(&got) {
+		t.Error("FieldList encoding differs from the struct encoding")
+	}
+	
+	fields, err := BytesFields([]byte{1}, []byte{2}, []byte{3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !EqualSlices(fields, list) {
+		t.Error("BytesFields should right-align short fields")
+	}
+	if _, err := BytesFields(make([]byte, 33)); err == nil {
+		t.Error("BytesFields should reject fields over 32 bytes")
+	}
+}
+
+// TestFromMontgomeryLimbs tests validated construction from Montgomery limbs
+func TestFromMontgomeryLimbs(t *testing.T) {
+	one := One()
+	got, err := FromMontgomeryLimbs([4]uint64(one))
+	if err != nil {
+		t.Fatalf("One() limbs rejected: %v", err)
+	}
+	if !got.Equal(&one) {
+		t.Error("One() limbs did not round-trip")
+	}
+	
+	// Raw limbs are Montgomery form, not the integer value
+	hundred, err := FromMontgomeryLimbs([4]uint64{100, 0, 0, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := FromUint64(100); hundred.Equal(&want) {
+		t.Error("limbs {100,0,0,0} should not equal FromUint64(100)")
+	}
+	
+	rMinusOne := rModulus
+	rMinusOne[0]--
+	if _, err := FromMontgomeryLimbs([4]uint64(rMinusOne)); err != nil {
+		t.Errorf("r-1 limbs rejected: %v", err)
+	}
+	
+	for _, limbs := range [][4]uint64{
+		[4]uint64(rModulus),
+		{^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)},
+		{0, 0, 0, rModulus[3] + 1},
+	} {
+		if _, err := FromMontgomeryLimbs(limbs); err == nil {
+			t.Errorf("out-of-range limbs %x accepted", limbs)
+		}
+	}
+}
+
+// TestHashWithLengthTag tests the length-in-capacity hashing mode
+func TestHashWithLengthTag(t *testing.T) {
+	// The capacity seed is 2^64 + n
+	twoTo64 := new(big.Int).Lsh(big.NewInt(1), 64)
+	for _, n := range []int{0, 1, 2, 100} {
+		want := new(big.Int).Add(twoTo64, big.NewInt(int64(n)))
+		if got := lengthTag(n).ToBigInt(); got.Cmp(want) != 0 {
+			t.Errorf("lengthTag(%d) = %s, want %s", n, got, want)
+		}
+	}
+	
+	a := FromUint64(1)
+	zero := Zero()
+	
+	// Trailing zeros must not collide across lengths
+	t1 := HashWithLengthTag(a)
+	t2 := HashWithLengthTag(a, zero)
+	if t1.Equal(&t2) {
+		t.Error("HashWithLengthTag(a) == HashWithLengthTag(a, 0)")
+	}
+	
+	results := []Fr{HashWithLengthTag(), HashWithLengthTag(zero), HashWithLengthTag(zero, zero), HashWithLengthTag(zero, zero, zero)}
+	for i := range results {
+		if results[i].IsZero() {
+			t.Errorf("length-tagged hash of %d zeros is zero", i)
+		}
+		for j := i + 1; j < len(results); j++ {
+			if results[i].Equal(&results[j]) {
+				t.Errorf("lengths %d and %d collided", i, j)
+			}
+		}
+	}
+}
+
+// TestEmptyHashNonZero checks the empty hash is one permutation of the zero state
+func TestEmptyHashNonZero(t *testing.T) {
+	empty := Hash()
+	zero := Zero()
+	if empty.Equal(&zero) {
+		t.Fatal("Hash() should not be Zero()")
+	}
+	
+	again := Hash()
+	if !empty.Equal(&again) {
+		t.Error("Hash() is not stable across calls")
+	}
+	
+	state := [T]Fr{Zero(), Zero(), Zero()}
+	ProductionPermutation(&state)
+	if !empty.Equal(&state[0]) {
+		t.Error("Hash() should equal the first element of Perm(0, 0, 0)")
+	}
+	
+	// Squeeze and a reset hasher follow the same convention
+	hasher := NewHasher()
+	if got := hasher.Squeeze(); !got.Equal(&empty) {
+		t.Error("Squeeze on a fresh hasher should equal Hash()")
+	}
+	hasher.Absorb(One())
+	hasher.Reset()
+	if got := hasher.Finalize(); !got.Equal(&empty) {
+		t.Error("Finalize after Reset should equal Hash()")
+	}
+}
+
+// TestCompress2MatchesHash2 checks Compress2 agrees with the sponge path of Hash2
+// The single-permutation count is checked by TestPermutationCallCount under the poseidon2_instrument tag
+func TestCompress2MatchesHash2(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for i := 0; i < 16; i++ {
+		a, b := randomFr(rng), randomFr(rng)
+		got := Compress2(a, b)
+		want := Hash2(a, b)
+		if !got.Equal(&want) {
+			t.Errorf("Compress2(%s, %s) = %s, want Hash2 = %s", a.String(), b.String(), got.String(), want.String())
+		}
+	}
+}
+
+// BenchmarkCompress2Sponge benchmarks the sponge path Compress2 used to take, for comparison
+func BenchmarkCompress2Sponge(b *testing.B) {
+	x := FromUint64(12345)
+	y := FromUint64(67890)
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hasher := NewHasher()
+		hasher.Absorb(x)
+		hasher.Absorb(y)
+		hasher.Finalize()
+	}
+}
+
+// TestTransposeStatesRoundTrip checks the SoA conversion and its inverse
+func TestTransposeStatesRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	for _, n := range []int{0, 1, 5, 64} {
+		states := make([][T]Fr, n)
+		for j := range states {
+			for i := 0; i < T; i++ {
+				states[j][i] = randomFr(rng)
+			}
+		}
+		
+		columns := TransposeStates(states)
+		for i := 0; i < T; i++ {
+			if len(columns[i]) != n {
+				t.Fatalf("column %d has length %d, want %d", i, len(columns[i]), n)
+			}
+			for j := 0; j < n; j++ {
+				if !columns[i][j].Equal(&states[j][i]) {
+					t.Fatalf("columns[%d][%d] != states[%d][%d]", i, j, j, i)
+				}
+			}
+		}
+		
+		back, err := UntransposeStates(columns)
+		if err != nil {
+			t.Fatalf("UntransposeStates failed: %v", err)
+		}
+		if len(back) != n {
+			t.Fatalf("round trip returned %d states, want %d", len(back), n)
+		}
+		for j := range back {
+			if back[j] != states[j] {
+				t.Errorf("state %d changed in round trip", j)
+			}
+		}
+	}
+	
+	var ragged [T][]Fr
+	ragged[0] = make([]Fr, 2)
+	ragged[1] = make([]Fr, 2)
+	ragged[2] = make([]Fr, 1)
+	if _, err := UntransposeStates(ragged); err == nil {
+		t.Error("UntransposeStates should reject columns of different lengths")
+	}
+}
+
+// TestPermutationApply checks the width-3 Permutation matches ProductionPermutation
+func TestPermutationApply(t *testing.T) {
+	perm, err := NewPermutation(T)
+	if err != nil {
+		t.Fatalf("NewPermutation(%d) failed: %v", T, err)
+	}
+	if perm.Width() != T {
+		t.Errorf("Width() = %d, want %d", perm.Width(), T)
+	}
+	
+	rng := rand.New(rand.NewSource(5))
+	for i := 0; i < 8; i++ {
+		var want [T]Fr
+		for j := range want {
+			want[j] = randomFr(rng)
+		}
+		got := append([]Fr(nil), want[:]...)
+		
+		ProductionPermutation(&want)
+		if err := perm.Apply(got); err != nil {
+			t.Fatalf("Apply failed: %v", err)
+		}
+		if !EqualSlices(got, want[:]) {
+			t.Errorf("Permutation.Apply differs from ProductionPermutation on input %d", i)
+		}
+	}
+	
+	wide, err := NewPermutation(4)
+	if err != nil {
+		t.Fatalf("NewPermutation(4) failed: %v", err)
+	}
+	initial := []Fr{One(), Zero(), Zero(), Zero()}
+	state := append([]Fr(nil), initial...)
+	if err := wide.Apply(state); err != nil {
+		t.Fatalf("width-4 Apply failed: %v", err)
+	}
+	if EqualSlices(state, initial) {
+		t.Error("width-4 permutation left the state unchanged")
+	}
+	
+	for _, width := range []int{-1, 0, 1, MaxPermutationWidth + 1} {
+		if _, err := NewPermutation(width); err == nil {
+			t.Errorf("NewPermutation(%d) should fail", width)
+		}
+	}
+}
+
+// TestPermutationApplyWrongLength checks wrong-length states return an error instead of panicking
+func TestPermutationApplyWrongLength(t *testing.T) {
+	perm, err := NewPermutation(T)
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	for _, n := range []int{0, 1, T - 1, T + 1, 16} {
+		state := make([]Fr, n)
+		for i := range state {
+			state[i] = FromUint64(uint64(i + 1))
+		}
+		before := append([]Fr(nil), state...)
+		
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("Apply panicked on a %d-element state: %v", n, r)
+				}
+			}()
+			if err := perm.Apply(state); err == nil {
+				t.Errorf("Apply should reject a %d-element state", n)
+			}
+		}()
+		
+		if !EqualSlices(state, before) {
+			t.Errorf("rejected %d-element state was modified", n)
+		}
+	}
+	
+	if err := perm.Apply(nil); err == nil {
+		t.Error("Apply should reject a nil state")
+	}
+}
+
+// TestEvalPoly checks Horner evaluation against hand-computed values and big.Int
+func TestEvalPoly(t *testing.T) {
+	// 3 + 2x + x^2 at x = 5 is 38
+	coeffs := []Fr{FromUint64(3), FromUint64(2), FromUint64(1)}
+	got := EvalPoly(coeffs, FromUint64(5))
+	if want := FromUint64(38); !got.Equal(&want) {
+		t.Errorf("EvalPoly(3+2x+x^2, 5) = %s, want 38", got.String())
+	}
+	
+	// 7 - x^3 at x = 2 is -1
+	minusOne := One()
+	minusOne.Neg(&minusOne)
+	got = EvalPoly([]Fr{FromUint64(7), Zero(), Zero(), minusOne}, FromUint64(2))
+	if !got.Equal(&minusOne) {
+		t.Errorf("EvalPoly(7-x^3, 2) = %s, want -1", got.String())
+	}
+	
+	if got := EvalPoly(nil, FromUint64(9)); !got.IsZero() {
+		t.Errorf("EvalPoly(nil, 9) = %s, want 0", got.String())
+	}
+	if got := EvalPoly(coeffs, Zero()); !got.Equal(&coeffs[0]) {
+		t.Errorf("EvalPoly at 0 = %s, want the constant term", got.String())
+	}
+	
+	modulus := limbsToBigInt(&rModulus)
+	rng := rand.New(rand.NewSource(17))
+	for trial := 0; trial < 20; trial++ {
+		coeffs := make([]Fr, rng.Intn(12))
+		for i := range coeffs {
+			coeffs[i] = randomFr(rng)
+		}
+		x := randomFr(rng)
+		
+		want := new(big.Int)
+		power := big.NewInt(1)
+		for i := range coeffs {
+			term := new(big.Int).Mul(coeffs[i].ToBigInt(), power)
+			want.Add(want, term)
+			power.Mul(power, x.ToBigInt())
+			power.Mod(power, modulus)
+		}
+		want.Mod(want, modulus)
+		
+		if got := EvalPoly(coeffs, x); got.ToBigInt().Cmp(want) != 0 {
+			t.Errorf("trial %d: EvalPoly = %s, want %s", trial, got.String(), want)
+		}
+	}
+}
+
+// TestDotProduct checks DotProduct and MulAdd on known vectors and against big.Int
+func TestDotProduct(t *testing.T) {
+	// (1, 2, 3) . (4, 5, 6) = 32
+	a := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	b := []Fr{FromUint64(4), FromUint64(5), FromUint64(6)}
+	got, err := DotProduct(a, b)
+	if err != nil {
+		t.Fatalf("DotProduct failed: %v", err)
+	}
+	if want := FromUint64(32); !got.Equal(&want) {
+		t.Errorf("DotProduct = %s, want 32", got.String())
+	}
+	
+	if got, err := DotProduct(nil, nil); err != nil || !got.IsZero() {
+		t.Errorf("DotProduct(nil, nil) = %s, %v; want 0", got.String(), err)
+	}
+	if _, err := DotProduct(a, b[:2]); err == nil {
+		t.Error("DotProduct should reject vectors of different lengths")
+	}
+	
+	acc := FromUint64(10)
+	x, y := FromUint64(6), FromUint64(7)
+	MulAdd(&acc, &x, &y)
+	if want := FromUint64(52); !acc.Equal(&want) {
+		t.Errorf("MulAdd(10, 6, 7) = %s, want 52", acc.String())
+	}
+	
+	modulus := limbsToBigInt(&rModulus)
+	rng := rand.New(rand.NewSource(23))
+	for trial := 0; trial < 20; trial++ {
+		n := rng.Intn(16)
+		a := make([]Fr, n)
+		b := make([]Fr, n)
+		want := new(big.Int)
+		for i := 0; i < n; i++ {
+			a[i], b[i] = randomFr(rng), randomFr(rng)
+			want.Add(want, new(big.Int).Mul(a[i].ToBigInt(), b[i].ToBigInt()))
+		}
+		want.Mod(want, modulus)
+		
+		got, err := DotProduct(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ToBigInt().Cmp(want) != 0 {
+			t.Errorf("trial %d: DotProduct = %s, want %s", trial, got.String(), want)
+		}
+	}
+}
+
+// TestSelect checks Select and cswap pick the expected operand
+func TestSelect(t *testing.T) {
+	a, b := FromUint64(3), FromUint64(9)
+	for _, c := range []int{0, 1, -1, 2, 1 << 40} {
+		var z Fr
+		z.Select(c, &a, &b)
+		want := b
+		if c == 0 {
+			want = a
+		}
+		if !z.Equal(&want) {
+			t.Errorf("Select(%d) = %s, want %s", c, z.String(), want.String())
+		}
+	}
+	
+	x, y := a, b
+	cswap(&x, &y, 0)
+	if !x.Equal(&a) || !y.Equal(&b) {
+		t.Error("cswap with bit 0 changed its operands")
+	}
+	cswap(&x, &y, 1)
+	if !x.Equal(&b) || !y.Equal(&a) {
+		t.Error("cswap with bit 1 did not swap")
+	}
+}
+
+// TestIsValid checks the canonical-form predicate at the modulus boundary
+func TestIsValid(t *testing.T) {
+	modulus := limbsToBigInt(&rModulus)
+	rMinusOne := FromBigInt(new(big.Int).Sub(modulus, big.NewInt(1)))
+	for _, x := range []Fr{Zero(), One(), rMinusOne} {
+		if !x.IsValid() {
+			t.Errorf("%s should be valid", x.String())
+		}
+	}
+	
+	rPlusOne := rModulus
+	rPlusOne[0]++
+	for _, x := range []Fr{rModulus, rPlusOne, {^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)}} {
+		if x.IsValid() {
+			t.Errorf("limbs %v should not be valid", x)
+		}
+	}
+}
+
+// TestPackBytes checks PackBytes is injective on length and content and feeds HashBytes
+func TestPackBytes(t *testing.T) {
+	var inputs [][]byte
+	for n := 0; n <= 64; n++ {
+		zeros := make([]byte, n)
+		inputs = append(inputs, zeros)
+		if n > 0 {
+			last := make([]byte, n)
+			last[n-1] = 1
+			inputs = append(inputs, last)
+		}
+		if n > 1 {
+			first := make([]byte, n)
+			first[0] = 1
+			inputs = append(inputs, first)
+		}
+	}
+	inputs = append(inputs, []byte("a"), []byte("\x00a"), []byte("a\x00"))
+	
+	limit := new(big.Int).Lsh(big.NewInt(1), 248)
+	seen := make(map[string]int)
+	for i, data := range inputs {
+		packed := PackBytes(data)
+		if want := (len(data)+30)/31 + 1; len(packed) != want {
+			t.Fatalf("PackBytes(%d bytes) returned %d elements, want %d", len(data), len(packed), want)
+		}
+		length := FromUint64(uint64(len(data)))
+		if !packed[len(packed)-1].Equal(&length) {
+			t.Errorf("PackBytes(%d bytes) does not end with the length", len(data))
+		}
+		
+		var key string
+		for _, e := range packed {
+			if e.ToBigInt().Cmp(limit) >= 0 {
+				t.Errorf("PackBytes(%x) produced an element >= 2^248", data)
+			}
+			key += FrKey(e)
+		}
+		if j, ok := seen[key]; ok {
+			t.Errorf("PackBytes(%x) collides with PackBytes(%x)", data, inputs[j])
+		}
+		seen[key] = i
+		
+		if len(data) == 0 {
+			continue
+		}
+		got, err := HashBytes(DomainGeneric, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := HashMany(DomainGeneric, packed...)
+		if got != want.ToBytes32() {
+			t.Errorf("HashBytes(%x) does not hash PackBytes output", data)
+		}
+	}
+}
+
+// TestPermute checks the slice entry point against ProductionPermutation and bad widths
+func TestPermute(t *testing.T) {
+	rng := rand.New(rand.NewSource(47))
+	for i := 0; i < 4; i++ {
+		var want [T]Fr
+		for j := range want {
+			want[j] = randomFr(rng)
+		}
+		got := append([]Fr(nil), want[:]...)
+		
+		ProductionPermutation(&want)
+		if err := Permute(got); err != nil {
+			t.Fatalf("Permute failed: %v", err)
+		}
+		if !EqualSlices(got, want[:]) {
+			t.Errorf("Permute differs from ProductionPermutation on input %d", i)
+		}
+	}
+	
+	// Other supported widths go through the matching Permutation
+	wide, err := NewPermutation(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := []Fr{FromUint64(1), FromUint64(2), FromUint64(3), FromUint64(4), FromUint64(5)}
+	b := append([]Fr(nil), a...)
+	if err := Permute(a); err != nil {
+		t.Fatalf("Permute on width 5 failed: %v", err)
+	}
+	if err := wide.Apply(b); err != nil {
+		t.Fatal(err)
+	}
+	if !EqualSlices(a, b) {
+		t.Error("Permute on width 5 differs from NewPermutation(5).Apply")
+	}
+	
+	for _, n := range []int{0, 1, MaxPermutationWidth + 1} {
+		if err := Permute(make([]Fr, n)); err == nil {
+			t.Errorf("Permute should reject a %d-element state", n)
+		}
+	}
+}
+
+// TestHalve checks Halve inverts Double and matches multiplication by 1/2
+func TestHalve(t *testing.T) {
+	modulus := limbsToBigInt(&rModulus)
+	inv2 := new(big.Int).ModInverse(big.NewInt(2), modulus)
+	rMinusOne := FromBigInt(new(big.Int).Sub(modulus, big.NewInt(1)))
+	
+	rng := rand.New(rand.NewSource(48))
+	inputs := []Fr{Zero(), One(), FromUint64(2), rMinusOne}
+	for i := 0; i < 50; i++ {
+		inputs = append(inputs, randomFr(rng))
+	}
+	
+	for _, x := range inputs {
+		var doubled, halved Fr
+		doubled.Double(&x)
+		halved.Halve(&doubled)
+		if !halved.Equal(&x) {
+			t.Errorf("Halve(Double(%s)) = %s", x.String(), halved.String())
+		}
+		
+		halved.Halve(&x)
+		if !isCanonical(&halved) {
+			t.Errorf("Halve(%s) is not canonical", x.String())
+		}
+		want := new(big.Int).Mul(x.ToBigInt(), inv2)
+		want.Mod(want, modulus)
+		if halved.ToBigInt().Cmp(want) != 0 {
+			t.Errorf("Halve(%s) = %s, want %s", x.String(), halved.String(), want)
+		}
+	}
+	
+	// In-place use
+	x := FromUint64(10)
+	x.Halve(&x)
+	if want := FromUint64(5); !x.Equal(&want) {
+		t.Errorf("in-place Halve(10) = %s, want 5", x.String())
+	}
+}
+
+// TestCube checks Cube against Exp(x, 3)
+func TestCube(t *testing.T) {
+	rng := rand.New(rand.NewSource(50))
+	three := big.NewInt(3)
+	for i := 0; i < 50; i++ {
+		x := randomFr(rng)
+		var got, want Fr
+		got.Cube(&x)
+		want.Exp(&x, three)
+		if !got.Equal(&want) {
+			t.Errorf("Cube(%s) = %s, want %s", x.String(), got.String(), want.String())
+		}
+	}
+	
+	x := FromUint64(4)
+	x.Cube(&x)
+	if want := FromUint64(64); !x.Equal(&want) {
+		t.Errorf("in-place Cube(4) = %s, want 64", x.String())
+	}
+}
+
+// TestPermutationSBoxDegree checks degree validation and that accepted S-boxes are invertible
+func TestPermutationSBoxDegree(t *testing.T) {
+	// 3 divides r-1 for BN254, so x^3 is not a bijection and must be rejected
+	for _, d := range []int{-1, 0, 1, 2, 3, 4, 9} {
+		if _, err := NewPermutationWithDegree(T, d); err == nil {
+			t.Errorf("NewPermutationWithDegree(%d, %d) should fail", T, d)
+		}
+	}
+	
+	perm5, err := NewPermutationWithDegree(T, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm5.Degree() != D {
+		t.Errorf("Degree() = %d, want %d", perm5.Degree(), D)
+	}
+	perm7, err := NewPermutationWithDegree(T, 7)
+	if err != nil {
+		t.Fatalf("NewPermutationWithDegree(%d, 7) failed: %v", T, err)
+	}
+	if perm7.Degree() != 7 {
+		t.Errorf("Degree() = %d, want 7", perm7.Degree())
+	}
+	
+	a := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	b := append([]Fr(nil), a...)
+	if err := perm5.Apply(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := perm7.Apply(b); err != nil {
+		t.Fatal(err)
+	}
+	if EqualSlices(a, b) {
+		t.Error("degree 5 and degree 7 permutations should differ")
+	}
+	
+	// x^d is inverted by x^e with e = d^-1 mod (r-1); the linear layers are invertible
+	// MDS matrices, so every round and hence the permutation is invertible
+	rMinusOne := new(big.Int).Sub(limbsToBigInt(&rModulus), big.NewInt(1))
+	rng := rand.New(rand.NewSource(53))
+	for _, d := range []int{5, 7} {
+		e := new(big.Int).ModInverse(big.NewInt(int64(d)), rMinusOne)
+		if e == nil {
+			t.Fatalf("degree %d has no inverse exponent", d)
+		}
+		for i := 0; i < 5; i++ {
+			x := randomFr(rng)
+			var y, back Fr
+			y.Exp(&x, big.NewInt(int64(d)))
+			back.Exp(&y, e)
+			if !back.Equal(&x) {
+				t.Errorf("x^%d is not inverted by x^%s", d, e)
+			}
+		}
+	}
+}
+
+// BenchmarkProductionPermutation benchmarks one permutation of a random state
+func BenchmarkProductionPermutation(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	state := [T]Fr{randomFr(rng), randomFr(rng), randomFr(rng)}
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ProductionPermutation(&state)
+	}
+}
+
+// permutationCeiling is the average time per permutation above which
+// TestPermutationPerformanceFloor fails. It sits well over an order of magnitude
+// above normal runs so slow or shared CI machines do not trip it
+const permutationCeiling = 5 * time.Millisecond
+
+// TestPermutationPerformanceFloor guards against catastrophic slowdowns in the permutation
+// It is not a benchmark: it only catches regressions such as a bit-by-bit Mul or a
+// reference MDS path left enabled, which cost orders of magnitude. Use
+// BenchmarkProductionPermutation to measure real throughput
+func TestPermutationPerformanceFloor(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping timing check in short mode")
+	}
+	
+	const runs = 200
+	var state [T]Fr
+	ProductionPermutation(&state) // Warm up
+	
+	start := time.Now()
+	for i := 0; i < runs; i++ {
+		ProductionPermutation(&state)
+	}
+	perOp := time.Since(start) / runs
+	
+	if perOp > permutationCeiling {
+		t.Errorf("permutation took %v per call, ceiling is %v", perOp, permutationCeiling)
+	}
+	t.Logf("permutation: %v per call", perOp)
+}
+
+// TestEqualCT checks EqualCT agrees with Equal, including single-limb differences
+// EqualCT ORs all limb differences before its only comparison, so it has no early return
+func TestEqualCT(t *testing.T) {
+	rng := rand.New(rand.NewSource(52))
+	values := []Fr{Zero(), One(), rModulus}
+	for i := 0; i < 20; i++ {
+		values = append(values, randomFr(rng))
+	}
+	
+	for _, a := range values {
+		for _, b := range values {
+			if got, want := a.EqualCT(&b), a.Equal(&b); got != want {
+				t.Errorf("EqualCT(%v, %v) = %v, Equal = %v", a, b, got, want)
+			}
+		}
+		
+		for limb := 0; limb < 4; limb++ {
+			for _, bit := range []uint{0, 31, 63} {
+				b := a
+				b[limb] ^= 1 << bit
+				if a.EqualCT(&b) {
+					t.Errorf("EqualCT missed a difference in limb %d bit %d", limb, bit)
+				}
+			}
+		}
+	}
+}
+
+// TestCommitOpen checks commitments are deterministic, randomized and open correctly
+func TestCommitOpen(t *testing.T) {
+	value := FromUint64(42)
+	r1 := FromUint64(0x1111)
+	r2 := FromUint64(0x2222)
+	
+	c1 := Commit(value, r1)
+	again := Commit(value, r1)
+	if !c1.Equal(&again) {
+		t.Error("same value and randomness should commit identically")
+	}
+	if want := Hash(r1, value); !c1.Equal(&want) {
+		t.Error("Commit should equal Hash(randomness, value)")
+	}
+	
+	c2 := Commit(value, r2)
+	if c1.Equal(&c2) {
+		t.Error("different randomness should give different commitments")
+	}
+	
+	if !Open(c1, value, r1) {
+		t.Error("commitment did not open with its value and randomness")
+	}
+	if Open(c1, FromUint64(43), r1) {
+		t.Error("commitment opened to a different value")
+	}
+	if Open(c1, value, r2) {
+		t.Error("commitment opened with different randomness")
+	}
+	
+	// Swapping the roles of value and randomness is a different commitment
+	if swapped := Commit(r1, value); swapped.Equal(&c1) {
+		t.Error("Commit should not be symmetric in value and randomness")
+	}
+}
+
+// TestSetHex checks SetHex on prefixed, short, full-width and invalid input
+func TestSetHex(t *testing.T) {
+	valid := []struct {
+		in   string
+		want Fr
+	}{
+		{"0x5", FromUint64(5)},
+		{"5", FromUint64(5)},
+		{"0X0a", FromUint64(10)},
+		{"0xdeadbeef", FromUint64(0xdeadbeef)},
+		{"0x0000000000000000000000000000000000000000000000000000000000000001", One()},
+		{"0x30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000001", Zero()}, // r reduces to 0
+	}
+	for _, tc := range valid {
+		var f Fr
+		if err := f.SetHex(tc.in); err != nil {
+			t.Errorf("SetHex(%q) failed: %v", tc.in, err)
+			continue
+		}
+		if !f.Equal(&tc.want) {
+			t.Errorf("SetHex(%q) = %s, want %s", tc.in, f.String(), tc.want.String())
+		}
+	}
+	
+	rng := rand.New(rand.NewSource(55))
+	for i := 0; i < 10; i++ {
+		x := randomFr(rng)
+		var f Fr
+		if err := f.SetHex(x.Hex()); err != nil || !f.Equal(&x) {
+			t.Errorf("SetHex(Hex(x)) round trip failed for %s: %v", x.String(), err)
+		}
+	}
+	
+	for _, in := range []string{"", "0x", "0xzz", "12g4", "0x" + strings.Repeat("1", 65), " 0x1"} {
+		f := FromUint64(7)
+		if err := f.SetHex(in); err == nil {
+			t.Errorf("SetHex(%q) should fail", in)
+		}
+		if want := FromUint64(7); !f.Equal(&want) {
+			t.Errorf("failed SetHex(%q) modified the receiver", in)
+		}
+	}
+}
+
+// TestProductionPermutationUnrolled checks the unrolled t=3 permutation against the generic loops
+func TestProductionPermutationUnrolled(t *testing.T) {
+	rng := rand.New(rand.NewSource(57))
+	inputs := [][T]Fr{{}, {One(), One(), One()}}
+	for i := 0; i < 20; i++ {
+		inputs = append(inputs, [T]Fr{randomFr(rng), randomFr(rng), randomFr(rng)})
+	}
+	
+	for i, in := range inputs {
+		unrolled, generic := in, in
+		productionPermutationUnrolled(&unrolled)
+		productionPermutationGeneric(&generic)
+		if unrolled != generic {
+			t.Errorf("input %d: unrolled permutation differs from the generic loops", i)
+		}
+	}
+}
+
+// BenchmarkProductionPermutationGeneric benchmarks the width-generic round loops for comparison
+func BenchmarkProductionPermutationGeneric(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	state := [T]Fr{randomFr(rng), randomFr(rng), randomFr(rng)}
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		productionPermutationGeneric(&state)
+	}
+}
+
+// TestFrUnmarshalJSON checks JSON numbers and strings decode to field elements
+func TestFrUnmarshalJSON(t *testing.T) {
+	valid := []struct {
+		in   string
+		want Fr
+	}{
+		{`5`, FromUint64(5)},
+		{`"0x5"`, FromUint64(5)},
+		{`"5"`, FromUint64(5)},
+		{` 0 `, Zero()},
+		{`18446744073709551615`, FromUint64(^uint64(0))},
+		{`"-1"`, func() Fr { f := One(); f.Neg(&f); return f }()},
+	}
+	for _, tc := range valid {
+		var f Fr
+		if err := json.Unmarshal([]byte(tc.in), &f); err != nil {
+			t.Errorf("Unmarshal(%s) failed: %v", tc.in, err)
+			continue
+		}
+		if !f.Equal(&tc.want) {
+			t.Errorf("Unmarshal(%s) = %s, want %s", tc.in, f.String(), tc.want.String())
+		}
+	}
+	
+	for _, in := range []string{`18446744073709551616`, `-1`, `5.0`, `1e3`, `"0xzz"`, `true`, `[1,2,3,4]`} {
+		var f Fr
+		if err := json.Unmarshal([]byte(in), &f); err == nil {
+			t.Errorf("Unmarshal(%s) should fail", in)
+		}
+	}
+	
+	// Inside a config struct
+	var cfg struct {
+		Key  Fr   `json:"key"`
+		Salt []Fr `json:"salt"`
+	}
+	if err := json.Unmarshal([]byte(`{"key": 7, "salt": ["0x1", 2]}`), &cfg); err != nil {
+		t.Fatalf("config unmarshal failed: %v", err)
+	}
+	if want := FromUint64(7); !cfg.Key.Equal(&want) {
+		t.Errorf("key = %s, want 7", cfg.Key.String())
+	}
+	if !EqualSlices(cfg.Salt, []Fr{FromUint64(1), FromUint64(2)}) {
+		t.Errorf("salt = %v, want [1 2]", cfg.Salt)
+	}
+}
+
+// TestFrSliceJSONRoundTrip checks []Fr decodes from and encodes to a JSON array of hex strings
+func TestFrSliceJSONRoundTrip(t *testing.T) {
+	input := `["0x1", "0x2", "0x30644e72e131a029b85045b68181585d2833e84879b9709143e1f593f0000000"]`
+	
+	var elems []Fr
+	if err := json.Unmarshal([]byte(input), &elems); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	one := One()
+	var minusOne Fr
+	minusOne.Neg(&one)
+	want := []Fr{FromUint64(1), FromUint64(2), minusOne}
+	if !EqualSlices(elems, want) {
+		t.Fatalf("decoded %v, want %v", frSliceToHexSlice(elems), frSliceToHexSlice(want))
+	}
+	
+	encoded, err := json.Marshal(elems)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expectedJSON := `["` + want[0].Hex() + `","` + want[1].Hex() + `","` + want[2].Hex() + `"]`
+	if string(encoded) != expectedJSON {
+		t.Errorf("Marshal = %s, want %s", encoded, expectedJSON)
+	}
+	
+	var decoded []Fr
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Unmarshal of re-encoded array failed: %v", err)
+	}
+	if !EqualSlices(decoded, elems) {
+		t.Errorf("round trip changed the elements: %v", frSliceToHexSlice(decoded))
+	}
+}
+
+// TestSumFr checks SumFr against folding Add
+func TestSumFr(t *testing.T) {
+	modulus := limbsToBigInt(&rModulus)
+	rMinusOne := FromBigInt(new(big.Int).Sub(modulus, big.NewInt(1)))
+	
+	rng := rand.New(rand.NewSource(59))
+	cases := [][]Fr{nil, {One()}, {rMinusOne, One()}}
+	maxed := make([]Fr, 1000)
+	for i := range maxed {
+		maxed[i] = rMinusOne // Forces many carries into the high limb
+	}
+	cases = append(cases, maxed)
+	for _, n := range []int{2, 7, 100, 5000} {
+		elements := make([]Fr, n)
+		for i := range elements {
+			elements[i] = randomFr(rng)
+		}
+		cases = append(cases, elements)
+	}
+	
+	for _, elements := range cases {
+		var want Fr
+		for i := range elements {
+			want.Add(&want, &elements[i])
+		}
+		got := SumFr(elements)
+		if !got.Equal(&want) {
+			t.Errorf("SumFr of %d elements = %s, want %s", len(elements), got.String(), want.String())
+		}
+		if !isCanonical(&got) {
+			t.Errorf("SumFr of %d elements is not canonical", len(elements))
+		}
+	}
+}
+
+// BenchmarkSumFr benchmarks the lazy accumulator over 10000 elements
+func BenchmarkSumFr(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	elements := make([]Fr, 10000)
+	for i := range elements {
+		elements[i] = randomFr(rng)
+	}
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		SumFr(elements)
+	}
+}
+
+// BenchmarkSumAdd benchmarks folding Add over 10000 elements for comparison
+func BenchmarkSumAdd(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	elements := make([]Fr, 10000)
+	for i := range elements {
+		elements[i] = randomFr(rng)
+	}
+	
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var sum Fr
+		for j := range elements {
+			sum.Add(&sum, &elements[j])
+		}
+	}
+}
+
+// TestInverse checks x * Inverse(x) == 1 and the zero convention
+func TestInverse(t *testing.T) {
+	rng := rand.New(rand.NewSource(61))
+	for i := 0; i < 20; i++ {
+		x := randomFr(rng)
+		if x.IsZero() {
+			continue
+		}
+		var inv, product Fr
+		inv.Inverse(&x)
+		product.Mul(&x, &inv)
+		if want := One(); !product.Equal(&want) {
+			t.Errorf("x * Inverse(x) = %s for x = %s", product.String(), x.String())
+		}
+	}
+	
+	var z Fr
+	if zero := Zero(); !z.Inverse(&zero).IsZero() {
+		t.Error("Inverse(0) should be 0")
+	}
+}
+
+// TestBatchInverseWithMask checks batch inversion with zeros mixed in
+func TestBatchInverseWithMask(t *testing.T) {
+	rng := rand.New(rand.NewSource(64))
+	elements := []Fr{Zero(), randomFr(rng), One(), Zero(), randomFr(rng), randomFr(rng), Zero()}
+	
+	inverses, zero := BatchInverseWithMask(elements)
+	if len(inverses) != len(elements) || len(zero) != len(elements) {
+		t.Fatalf("got %d inverses and %d mask entries for %d elements", len(inverses), len(zero), len(elements))
+	}
+	for i := range elements {
+		if zero[i] != elements[i].IsZero() {
+			t.Errorf("mask[%d] = %v, element is zero: %v", i, zero[i], elements[i].IsZero())
+		}
+		var want Fr
+		want.Inverse(&elements[i])
+		if !inverses[i].Equal(&want) {
+			t.Errorf("inverse %d = %s, want %s", i, inverses[i].String(), want.String())
+		}
+	}
+	
+	if got := BatchInverse(elements); !EqualSlices(got, inverses) {
+		t.Error("BatchInverse differs from BatchInverseWithMask")
+	}
+	
+	// Edge cases: empty and all-zero inputs
+	if inv, mask := BatchInverseWithMask(nil); len(inv) != 0 || len(mask) != 0 {
+		t.Error("BatchInverseWithMask(nil) should return empty slices")
+	}
+	inv, mask := BatchInverseWithMask([]Fr{Zero(), Zero()})
+	if !mask[0] || !mask[1] || !inv[0].IsZero() || !inv[1].IsZero() {
+		t.Error("all-zero input should give zero inverses and a full mask")
+	}
+}
+
+// TestHashVarUints checks the length prefix separates trailing zeros
+func TestHashVarUints(t *testing.T) {
+	short := HashVarUints(DomainGeneric, []uint64{1, 2})
+	long := HashVarUints(DomainGeneric, []uint64{1, 2, 0})
+	if short.Equal(&long) {
+		t.Error("appending a trailing zero should change HashVarUints")
+	}
+	
+	empty := HashVarUints(DomainGeneric, nil)
+	zero := HashVarUints(DomainGeneric, []uint64{0})
+	if empty.Equal(&zero) {
+		t.Error("empty and single-zero sequences should hash differently")
+	}
+	
+	other := HashVarUints(DomainPOETNode, []uint64{1, 2})
+	if short.Equal(&other) {
+		t.Error("HashVarUints should depend on the domain tag")
+	}
+	
+	if seq := HashMany(DomainGeneric, FromUint64(2), FromUint64(1), FromUint64(2)); short.Equal(&seq) {
+		t.Error("HashVarUints collides with HashMany of tag, length and values")
+	}
+	iv := FromUint64(uint64(domainVarUints))
+	if want := HashWithIV(iv, FromUint64(uint64(DomainGeneric)), FromUint64(2), FromUint64(1), FromUint64(2)); !short.Equal(&want) {
+		t.Error("HashVarUints should seed the capacity with domainVarUints and absorb tag, length and values")
+	}
+}
+
+// TestLabelToFr checks that labels map to distinct, stable field elements
+func TestLabelToFr(t *testing.T) {
+	labels := []string{"", "a", "b", "ab", "challenge", "challenge ", "Challenge", "poseidon2/challenge"}
+	seen := make(map[Fr]string)
+	for _, label := range labels {
+		f := LabelToFr(label)
+		if !f.IsValid() {
+			t.Errorf("LabelToFr(%q) is not canonical", label)
+		}
+		if prev, ok := seen[f]; ok {
+			t.Errorf("LabelToFr(%q) collides with LabelToFr(%q)", label, prev)
+		}
+		seen[f] = label
+		
+		if again := LabelToFr(label); !again.Equal(&f) {
+			t.Errorf("LabelToFr(%q) is not deterministic", label)
+		}
+	}
+	
+	// Pinned so a change in the label encoding is caught across runs and builds
+	want, _ := hexToFr("0x209ce3ea71962b09903c11b67e9e93478043a4b4a370fdf51b0d73c57870724a")
+	if got := LabelToFr("poseidon2/challenge"); !got.Equal(&want) {
+		t.Errorf("LabelToFr(\"poseidon2/challenge\") = %s, want %s", got.Hex(), want.Hex())
+	}
+	
+	digest, _ := HashBytes(domainLabel, []byte("challenge"))
+	if got := LabelToFr("challenge"); got.ToBytes32() != digest {
+		t.Error("LabelToFr differs from HashBytes with the label domain")
+	}
+}
+
+// TestHashLargeBytes streams a multi-megabyte reader and checks it against HashBytes
+func TestHashLargeBytes(t *testing.T) {
+	rng := rand.New(rand.NewSource(72))
+	data := make([]byte, 2<<20+17) // Not a multiple of the 31-byte group size
+	rng.Read(data)
+	
+	// Prefixes around group and buffer boundaries match HashBytes exactly
+	for _, n := range []int{0, 1, 30, 31, 32, 62, 1000, MaxInputSize, MaxInputSize + 1} {
+		want, err := HashBytes(DomainGeneric, data[:n])
+		if err != nil {
+			t.Fatalf("HashBytes(%d bytes) failed: %v", n, err)
+		}
+		got, err := HashLargeBytes(DomainGeneric, io.LimitReader(bytes.NewReader(data), int64(n)))
+		if err != nil {
+			t.Fatalf("HashLargeBytes(%d bytes) failed: %v", n, err)
+		}
+		if got != want {
+			t.Errorf("HashLargeBytes differs from HashBytes for a %d-byte prefix", n)
+		}
+	}
+	
+	generic, _ := HashLargeBytes(DomainGeneric, bytes.NewReader(data[:100]))
+	if other, _ := HashLargeBytes(DomainPOETNode, bytes.NewReader(data[:100])); other == generic {
+		t.Error("HashLargeBytes ignores the domain tag")
+	}
+	
+	readErr := errors.New("read failed")
+	if _, err := HashLargeBytes(DomainGeneric, io.MultiReader(bytes.NewReader(data[:100]), iotest.ErrReader(readErr))); !errors.Is(err, readErr) {
+		t.Errorf("HashLargeBytes should surface read errors, got %v", err)
+	}
+	
+	if testing.Short() {
+		t.Skip("skipping multi-megabyte stream in short mode")
+	}
+	full, err := HashLargeBytes(DomainGeneric, bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("HashLargeBytes failed on %d bytes: %v", len(data), err)
+	}
+	if want, _ := HashBytes(DomainGeneric, data); full != want {
+		t.Error("HashLargeBytes differs from HashBytes on the full input")
+	}
+}
+
+// TestPowersOfTwo compares the table against big.Int powers and checks the bounds
+func TestPowersOfTwo(t *testing.T) {
+	powers, err := PowersOfTwo(256)
+	if err != nil {
+		t.Fatalf("PowersOfTwo(256) failed: %v", err)
+	}
+	if len(powers) != 256 {
+		t.Fatalf("PowersOfTwo(256) returned %d elements", len(powers))
+	}
+	for i, p := range powers {
+		want := FromBigInt(new(big.Int).Lsh(big.NewInt(1), uint(i)))
+		if !p.Equal(&want) {
+			t.Fatalf("PowersOfTwo(256)[%d] = %s, want 2^%d", i, p.String(), i)
+		}
+	}
+	
+	// Recomposing a bit decomposition
+	bits := make([]Fr, 64)
+	v := uint64(0xdeadbeefcafe1234)
+	for i := range bits {
+		if v>>uint(i)&1 == 1 {
+			bits[i] = One()
+		}
+	}
+	got, err := DotProduct(bits, powers[:64])
+	if err != nil {
+		t.Fatalf("DotProduct failed: %v", err)
+	}
+	if want := FromUint64(v); !got.Equal(&want) {
+		t.Errorf("recomposition = %s, want %d", got.String(), v)
+	}
+	
+	if empty, err := PowersOfTwo(0); err != nil || len(empty) != 0 {
+		t.Errorf("PowersOfTwo(0) = %v, %v; want empty", empty, err)
+	}
+	for _, n := range []int{-1, 257, 1000} {
+		if _, err := PowersOfTwo(n); err == nil {
+			t.Errorf("PowersOfTwo(%d) should fail", n)
+		}
+	}
+}
+
+// TestHashCounted checks that the element count separates inputs Hash confuses
+func TestHashCounted(t *testing.T) {
+	a := FromUint64(5)
+	
+	// Hash(a) and Hash(a, Zero()) permute the same state; the count separates them
+	if h1, h2 := HashCounted(a), HashCounted(a, Zero()); h1.Equal(&h2) {
+		t.Error("HashCounted(a) == HashCounted(a, Zero())")
+	}
+	if h1, h2 := HashCounted(), HashCounted(Zero()); h1.Equal(&h2) {
+		t.Error("HashCounted() == HashCounted(Zero())")
+	}
+	
+	// Count then elements, through a sponge seeded with domainCounted
+	elements := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	counted := append([]Fr{FromUint64(3)}, elements...)
+	want := HashWithIV(FromUint64(uint64(domainCounted)), counted...)
+	got := HashCounted(elements...)
+	if !got.Equal(&want) {
+		t.Error("HashCounted does not absorb the count before the elements")
+	}
+	if plain := Hash(counted...); got.Equal(&plain) {
+		t.Error("HashCounted collides with Hash of the count and elements")
+	}
+}
+
+// TestCanonicalize feeds limbs at and above r and compares against big.Int mod r
+func TestCanonicalize(t *testing.T) {
+	modulus := limbsToBigInt(&rModulus)
+	minusOne := rModulus
+	minusOne[0]--
+	plus := func(k uint64) Fr {
+		var z Fr
+		z.add(&rModulus, &Fr{k, 0, 0, 0})
+		return z
+	}
+	twoR := rModulus
+	twoR.add(&rModulus, &rModulus)
+	
+	cases := map[string]Fr{
+		"zero":    {},
+		"r-1":     minusOne,
+		"r":       rModulus,
+		"r+1":     plus(1),
+		"r+12345": plus(12345),
+		"2r":      twoR,
+		"2^256-1": {^uint64(0), ^uint64(0), ^uint64(0), ^uint64(0)},
+	}
+	for name, in := range cases {
+		got := Canonicalize(in)
+		if !got.IsValid() {
+			t.Errorf("Canonicalize(%s) is not canonical", name)
+		}
+		want := new(big.Int).Mod(limbsToBigInt(&in), modulus)
+		if limbsToBigInt(&got).Cmp(want) != 0 {
+			t.Errorf("Canonicalize(%s) = %s, want %s", name, limbsToBigInt(&got), want)
+		}
+	}
+	
+	// Canonical input is returned unchanged
+	x := FromUint64(42)
+	if got := Canonicalize(x); got != x {
+		t.Error("Canonicalize changed a canonical element")
+	}
+}
+
+// Field benchmarks store their final result in a package-level sink and feed each
+// result into the next iteration, so the compiler can neither drop the call as dead
+// code nor hoist it out of the loop
+
+var (
+	benchSinkFr    Fr
+	benchSinkSlice []Fr
+)
+
+// benchOperands returns two fixed, full-width field elements
+func benchOperands() (Fr, Fr) {
+	x, _ := ParseField("0x1a2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a3b4c5d6e7f80")
+	y, _ := ParseField("0x0fedcba9876543210fedcba9876543210fedcba9876543210fedcba987654321")
+	return x, y
+}
+
+// BenchmarkAdd benchmarks field addition
+func BenchmarkAdd(b *testing.B) {
+	z, y := benchOperands()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Add(&z, &y)
+	}
+	benchSinkFr = z
+}
+
+// BenchmarkSub benchmarks field subtraction
+func BenchmarkSub(b *testing.B) {
+	z, y := benchOperands()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Sub(&z, &y)
+	}
+	benchSinkFr = z
+}
+
+// BenchmarkMul benchmarks Montgomery multiplication
+func BenchmarkMul(b *testing.B) {
+	z, y := benchOperands()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Mul(&z, &y)
+	}
+	benchSinkFr = z
+}
+
+// BenchmarkSquare benchmarks field squaring
+func BenchmarkSquare(b *testing.B) {
+	z, _ := benchOperands()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Square(&z)
+	}
+	benchSinkFr = z
+}
+
+// BenchmarkInverse benchmarks a single field inversion
+func BenchmarkInverse(b *testing.B) {
+	z, _ := benchOperands()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Inverse(&z)
+	}
+	benchSinkFr = z
+}
+
+// BenchmarkBatchInverse benchmarks inverting 256 elements with Montgomery's trick
+func BenchmarkBatchInverse(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	elements := make([]Fr, 256)
+	for i := range elements {
+		elements[i] = randomFr(rng)
+	}
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		benchSinkSlice = BatchInverse(elements)
+	}
+}
+
+// TestPermuteCopy checks PermuteCopy matches the in-place permutation without mutating its input
+func TestPermuteCopy(t *testing.T) {
+	input := [T]Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	original := input
+	
+	got := PermuteCopy(input)
+	if input != original {
+		t.Error("PermuteCopy mutated its input")
+	}
+	
+	want := input
+	ProductionPermutation(&want)
+	if got != want {
+		t.Error("PermuteCopy differs from ProductionPermutation on a copy")
+	}
+}
+
+// TestHashAddress checks address alignment and that distinct addresses and tags separate
+func TestHashAddress(t *testing.T) {
+	var a, b [20]byte
+	for i := range a {
+		a[i] = byte(i + 1)
+		b[i] = byte(i + 1)
+	}
+	b[19] ^= 1
+	
+	ha := HashAddress(DomainGeneric, a)
+	if hb := HashAddress(DomainGeneric, b); ha.Equal(&hb) {
+		t.Error("distinct addresses hash to the same element")
+	}
+	if other := HashAddress(DomainPOETNode, a); ha.Equal(&other) {
+		t.Error("HashAddress ignores the domain tag")
+	}
+	
+	// The address is right-aligned: it equals the element of its uint160 value
+	value := FromBigInt(new(big.Int).SetBytes(a[:]))
+	if want := Hash(FromUint64(uint64(DomainGeneric)), value); !ha.Equal(&want) {
+		t.Error("HashAddress does not absorb the address as a right-aligned word")
+	}
+	
+	// A low address is the same element as FromUint64 of its value
+	var low [20]byte
+	low[19] = 0x2a
+	if got, want := HashAddress(DomainGeneric, low), Hash(FromUint64(uint64(DomainGeneric)), FromUint64(0x2a)); !got.Equal(&want) {
+		t.Error("address 0x...2a is not aligned to the integer 42")
+	}
+}
+
+// TestHashBytesWithEndian compares the two byte orders against each other and HashBytes
+func TestHashBytesWithEndian(t *testing.T) {
+	rng := rand.New(rand.NewSource(77))
+	for _, n := range []int{2, 5, 30, 31, 32, 62, 100} {
+		data := make([]byte, n)
+		rng.Read(data)
+		data[0] = 0x01 // Asymmetric: reversing the bytes changes the value
+		data[n-1] = 0xFE
+		
+		be, err := HashBytesWithEndian(DomainGeneric, false, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, _ := HashBytes(DomainGeneric, data); be != want {
+			t.Errorf("n=%d: big-endian mode differs from HashBytes", n)
+		}
+		
+		// Every byte-hashing path shares the PackBytes encoding
+		packed := Hash(append([]Fr{FromUint64(uint64(DomainGeneric))}, PackBytes(data)...)...)
+		if be != packed.ToBytes32() {
+			t.Errorf("n=%d: HashBytes is not the hash of tag and PackBytes", n)
+		}
+		if ct := HashBytesCT(DomainGeneric, data); ct != be {
+			t.Errorf("n=%d: HashBytesCT differs from HashBytes", n)
+		}
+		if large, err := HashLargeBytes(DomainGeneric, bytes.NewReader(data)); err != nil || large != be {
+			t.Errorf("n=%d: HashLargeBytes differs from HashBytes (err %v)", n, err)
+		}
+		
+		le, err := HashBytesWithEndian(DomainGeneric, true, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if le == be {
+			t.Errorf("n=%d: byte orders agree on asymmetric input", n)
+		}
+		if again, _ := HashBytesWithEndian(DomainGeneric, true, data); again != le {
+			t.Errorf("n=%d: little-endian mode is not deterministic", n)
+		}
+		
+		// Within one group, little-endian data is big-endian of the reversed bytes
+		if n <= 31 {
+			reversed := make([]byte, n)
+			for i := range data {
+				reversed[n-1-i] = data[i]
+			}
+			if want, _ := HashBytes(DomainGeneric, reversed); le != want {
+				t.Errorf("n=%d: little-endian group does not match reversed big-endian", n)
+			}
+		}
+	}
+	
+	// Chunking behaves the same in both modes
+	a, b := []byte("left"), []byte("right")
+	for _, littleEndian := range []bool{false, true} {
+		split, _ := HashBytesWithEndian(DomainGeneric, littleEndian, a, nil, b)
+		joined, _ := HashBytesWithEndian(DomainGeneric, littleEndian, a, b)
+		if split != joined {
+			t.Errorf("littleEndian=%v: empty chunk changed the digest", littleEndian)
+		}
+	}
+}
+
+// TestHasherState checks State and Absorbed around the rate-triggered permutation
+func TestHasherState(t *testing.T) {
+	h := NewHasher()
+	if h.Absorbed() != 0 || h.State() != [T]Fr{} {
+		t.Fatal("fresh hasher should have an empty block and zero state")
+	}
+	
+	a, b := FromUint64(3), FromUint64(4)
+	h.Absorb(a)
+	if h.Absorbed() != 1 {
+		t.Errorf("Absorbed() = %d after one element, want 1", h.Absorbed())
+	}
+	if state := h.State(); state != [T]Fr{a, Zero(), Zero()} {
+		t.Error("State should hold the absorbed element before the permutation")
+	}
+	
+	// Mutating the copy does not touch the hasher
+	state := h.State()
+	state[0] = b
+	if got := h.State(); !got[0].Equal(&a) {
+		t.Error("State returned a reference to the internal state")
+	}
+	
+	// Filling the rate permutes and starts a new block
+	h.Absorb(b)
+	if h.Absorbed() != 0 {
+		t.Errorf("Absorbed() = %d after a full block, want 0", h.Absorbed())
+	}
+	want := [T]Fr{a, b, Zero()}
+	ProductionPermutation(&want)
+	if h.State() != want {
+		t.Error("State after a full block should be the permuted state")
+	}
+}
+
+// TestDetectSpongeCollision checks the raw sponge's zero-padding collision and that a count prefix removes it
+func TestDetectSpongeCollision(t *testing.T) {
+	x := FromUint64(9)
+	
+	if !DetectSpongeCollision([]Fr{x}, []Fr{x}) {
+		t.Error("identical inputs should collide")
+	}
+	if !DetectSpongeCollision([]Fr{x}, []Fr{x, Zero()}) {
+		t.Error("[x] and [x, 0] should collide in the unpadded sponge")
+	}
+	if DetectSpongeCollision([]Fr{x}, []Fr{FromUint64(10)}) {
+		t.Error("distinct single elements should not collide")
+	}
+	if DetectSpongeCollision(nil, []Fr{x}) {
+		t.Error("empty input should not collide with [x]")
+	}
+	
+	// With the length absorbed first, as HashCounted does, distinct lengths never collide
+	counted := func(elements ...Fr) []Fr {
+		return append([]Fr{FromUint64(uint64(len(elements)))}, elements...)
+	}
+	inputs := [][]Fr{{}, {Zero()}, {x}, {x, Zero()}, {x, Zero(), Zero()}, {Zero(), x}}
+	for i := range inputs {
+		for j := range inputs {
+			if i != j && DetectSpongeCollision(counted(inputs[i]...), counted(inputs[j]...)) {
+				t.Errorf("count-prefixed inputs %d and %d collide", i, j)
+			}
+		}
+	}
+}
+
+// TestSquareMatchesMul differentially tests the dedicated squaring against Mul(x, x)
+func TestSquareMatchesMul(t *testing.T) {
+	minusOne := rModulus
+	minusOne[0]--
+	minusOne.Mul(&minusOne, &montgomeryR2) // r-1 in Montgomery form
+	edges := []Fr{Zero(), One(), minusOne, FromUint64(^uint64(0)), {^uint64(0), 0, 0, 0}, {0, 0, 0, 1 << 61}}
+	
+	rng := rand.New(rand.NewSource(81))
+	inputs := edges
+	for i := 0; i < 100000; i++ {
+		inputs = append(inputs, randomFr(rng))
+	}
+	
+	for i, x := range inputs {
+		var sq, mul Fr
+		sq.Square(&x)
+		mul.Mul(&x, &x)
+		if sq != mul {
+			t.Fatalf("input %d: Square = %v, Mul(x, x) = %v", i, sq, mul)
+		}
+	}
+	
+	// Aliasing z and x
+	x := randomFr(rng)
+	want := x
+	want.Mul(&want, &want)
+	x.Square(&x)
+	if x != want {
+		t.Error("Square with z == x differs from Mul")
+	}
+}
+
+// BenchmarkMulSelf benchmarks Mul(x, x) for comparison with BenchmarkSquare
+func BenchmarkMulSelf(b *testing.B) {
+	z, _ := benchOperands()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		z.Mul(&z, &z)
+	}
+	benchSinkFr = z
+}
+
+// TestMulBarrett checks the Barrett backend against Mul after leaving Montgomery form
+func TestMulBarrett(t *testing.T) {
+	one := Fr{1, 0, 0, 0}
+	regular := func(x Fr) Fr {
+		var v Fr
+		v.MulCIOS(&x, &one) // x*R * 1 * R^-1 = x
+		return v
+	}
+	
+	rng := rand.New(rand.NewSource(82))
+	minusOne := rModulus
+	minusOne[0]--
+	pairs := [][2]Fr{
+		{Zero(), randomFr(rng)},
+		{One(), randomFr(rng)},
+		{FromBigInt(limbsToBigInt(&minusOne)), FromBigInt(limbsToBigInt(&minusOne))},
+	}
+	for i := 0; i < 5000; i++ {
+		pairs = append(pairs, [2]Fr{randomFr(rng), randomFr(rng)})
+	}
+	
+	modulus := limbsToBigInt(&rModulus)
+	for i, pair := range pairs {
+		x, y := regular(pair[0]), regular(pair[1])
+		var got Fr
+		got.MulBarrett(&x, &y)
+		
+		var product Fr
+		product.Mul(&pair[0], &pair[1])
+		if want := regular(product); got != want {
+			t.Fatalf("pair %d: MulBarrett = %v, Mul = %v", i, got, want)
+		}
+		if !got.IsValid() {
+			t.Fatalf("pair %d: MulBarrett result is not canonical", i)
+		}
+		
+		want := new(big.Int).Mul(limbsToBigInt(&x), limbsToBigInt(&y))
+		if limbsToBigInt(&got).Cmp(want.Mod(want, modulus)) != 0 {
+			t.Fatalf("pair %d: MulBarrett differs from big.Int", i)
+		}
+	}
+}
+
+// TestFromInt64 checks the signed conversion convention
+func TestFromInt64(t *testing.T) {
+	one := One()
+	var minusOne Fr
+	minusOne.Sub(&Fr{}, &one)
+	if got := FromInt64(-1); !got.Equal(&minusOne) {
+		t.Errorf("FromInt64(-1) = %s, want Zero - One", got.String())
+	}
+	if got, want := FromInt64(5), FromUint64(5); !got.Equal(&want) {
+		t.Errorf("FromInt64(5) = %s, want FromUint64(5)", got.String())
+	}
+	if got := FromInt64(0); !got.IsZero() {
+		t.Error("FromInt64(0) should be zero")
+	}
+	
+	modulus := limbsToBigInt(&rModulus)
+	for _, x := range []int64{-2, -12345, 1 << 40, math.MaxInt64, math.MinInt64, math.MinInt64 + 1} {
+		want := FromBigInt(new(big.Int).Mod(big.NewInt(x), modulus))
+		if got := FromInt64(x); !got.Equal(&want) {
+			t.Errorf("FromInt64(%d) = %s, want x mod r", x, got.String())
+		}
+	}
+	
+	// Negation and addition agree with integer arithmetic
+	sum := FromInt64(-7)
+	seven := FromInt64(7)
+	if sum.Add(&sum, &seven); !sum.IsZero() {
+		t.Error("FromInt64(-7) + FromInt64(7) != 0")
+	}
+}
+
+// TestProductionPermutationChecked checks the validating entry point
+func TestProductionPermutationChecked(t *testing.T) {
+	state := [T]Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	want := state
+	ProductionPermutation(&want)
+	if err := ProductionPermutationChecked(&state); err != nil {
+		t.Fatalf("canonical state rejected: %v", err)
+	}
+	if state != want {
+		t.Error("checked permutation differs from ProductionPermutation")
+	}
+	
+	for bad := 0; bad < T; bad++ {
+		state := [T]Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+		state[bad] = rModulus // r itself is the smallest non-canonical value
+		original := state
+		
+		err := ProductionPermutationChecked(&state)
+		if err == nil {
+			t.Fatalf("non-canonical element at index %d was accepted", bad)
+		}
+		if !strings.Contains(err.Error(), fmt.Sprintf("element %d ", bad)) {
+			t.Errorf("error %q does not name index %d", err, bad)
+		}
+		if limbs := fmt.Sprintf("limbs [%x %x %x %x]", rModulus[0], rModulus[1], rModulus[2], rModulus[3]); !strings.Contains(err.Error(), limbs) {
+			t.Errorf("error %q does not print the limbs as %q", err, limbs)
+		}
+		if state != original {
+			t.Errorf("state was modified despite the error at index %d", bad)
+		}
+	}
+}
+
+// TestEstimateComplexityWithWeights checks zeroed and custom heuristic weights
+func TestEstimateComplexityWithWeights(t *testing.T) {
+	zeros := make([]byte, 5000) // Trips repetition, diversity, dominance and null-byte heuristics
+	
+	if got, want := EstimateComplexityWithWeights(zeros, DefaultComplexityWeights()), estimateComplexity(zeros); got != want {
+		t.Errorf("default weights give %d, estimateComplexity gives %d", got, want)
+	}
+	
+	// Zeroed weights leave only the size component
+	if got, want := EstimateComplexityWithWeights(zeros, ComplexityWeights{}), len(zeros)/100; got != want {
+		t.Errorf("zero weights give %d, want the size-based %d", got, want)
+	}
+	
+	// Disabling repetition lets a zero buffer through that the defaults reject
+	if estimateComplexity(zeros) <= MaxComplexityScore {
+		t.Fatal("test buffer should exceed MaxComplexityScore with the default weights")
+	}
+	w := DefaultComplexityWeights()
+	w.Repetition = 0
+	if got := EstimateComplexityWithWeights(zeros, w); got > MaxComplexityScore {
+		t.Errorf("score without repetition penalty = %d, want at most %d", got, MaxComplexityScore)
+	}
+	
+	// A single heuristic contributes exactly its weight
+	base := EstimateComplexityWithWeights(zeros, ComplexityWeights{})
+	if got := EstimateComplexityWithWeights(zeros, ComplexityWeights{NullBytes: 7}); got != base+7 {
+		t.Errorf("NullBytes weight 7 gives %d, want %d", got, base+7)
+	}
+	if got := EstimateComplexityWithWeights(zeros, ComplexityWeights{MaxBytes: 7}); got != base {
+		t.Errorf("MaxBytes weight should not apply to a zero buffer, got %d", got)
+	}
+}
+
+// TestHashBytesBatch checks batch output order and validation against HashBytes
+func TestHashBytesBatch(t *testing.T) {
+	rng := rand.New(rand.NewSource(88))
+	inputs := make([][]byte, 257)
+	for i := range inputs {
+		inputs[i] = make([]byte, 1+rng.Intn(100))
+		rng.Read(inputs[i])
+	}
+	
+	for _, workers := range []int{0, 1, 3, 8, 1000} {
+		got, err := HashBytesBatch(DomainPOETNode, inputs, workers)
+		if err != nil {
+			t.Fatalf("workers=%d: %v", workers, err)
+		}
+		if len(got) != len(inputs) {
+			t.Fatalf("workers=%d: %d outputs for %d inputs", workers, len(got), len(inputs))
+		}
+		for i, data := range inputs {
+			if want, _ := HashBytes(DomainPOETNode, data); got[i] != want {
+				t.Fatalf("workers=%d: output %d differs from HashBytes", workers, i)
+			}
+		}
+	}
+	
+	if got, err := HashBytesBatch(DomainGeneric, nil, 4); err != nil || len(got) != 0 {
+		t.Errorf("empty batch = %v, %v; want no outputs and no error", got, err)
+	}
+	
+	bad := append([][]byte{}, inputs[:3]...)
+	bad = append(bad, nil)
+	if _, err := HashBytesBatch(DomainGeneric, bad, 2); err == nil || !strings.Contains(err.Error(), "input 3") {
+		t.Errorf("empty input should be rejected with its index, got %v", err)
+	}
+	bad[3] = make([]byte, MaxInputSize+1)
+	if _, err := HashBytesBatch(DomainGeneric, bad, 2); err == nil {
+		t.Error("oversized input should be rejected")
+	}
+}
+
+// batchBenchInputs returns 10k small messages for the batch benchmarks
+func batchBenchInputs() [][]byte {
+	rng := rand.New(rand.NewSource(1))
+	inputs := make([][]byte, 10000)
+	for i := range inputs {
+		inputs[i] = make([]byte, 64)
+		rng.Read(inputs[i])
+	}
+	return inputs
+}
+
+// BenchmarkHashBytesBatch benchmarks 10k small inputs on GOMAXPROCS workers
+func BenchmarkHashBytesBatch(b *testing.B) {
+	inputs := batchBenchInputs()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := HashBytesBatch(DomainPOETNode, inputs, 0); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkHashBytesSerial benchmarks the same 10k inputs in a plain loop for comparison
+func BenchmarkHashBytesSerial(b *testing.B) {
+	inputs := batchBenchInputs()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, data := range inputs {
+			if _, err := HashBytes(DomainPOETNode, data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// TestSetUint64 checks SetUint64 against FromUint64 and that it returns its receiver
+func TestSetUint64(t *testing.T) {
+	for _, x := range []uint64{0, 1, 2, 1 << 32, 0xdeadbeefcafebabe, ^uint64(0)} {
+		z := FromUint64(99) // Nonzero start so a missed write is caught
+		got := z.SetUint64(x)
+		if got != &z {
+			t.Fatalf("SetUint64(%d) did not return its receiver", x)
+		}
+		if want := FromUint64(x); !z.Equal(&want) {
+			t.Errorf("SetUint64(%d) = %s, want FromUint64", x, z.String())
+		}
+	}
+	
+	// Chaining into another operation
+	var a, b Fr
+	b.SetUint64(4)
+	a.Add(a.SetUint64(3), &b)
+	if want := FromUint64(7); !a.Equal(&want) {
+		t.Errorf("chained SetUint64 then Add = %s, want 7", a.String())
+	}
+}
+
+// TestTranscriptMarshalResume checks that a transcript serialized mid-protocol resumes identically
+func TestTranscriptMarshalResume(t *testing.T) {
+	commitments := []Fr{FromUint64(11), FromUint64(22), FromUint64(33)}
+	evaluations := []Fr{FromUint64(44)}
+	
+	// Uninterrupted run
+	full := NewTranscript("poseidon2-test")
+	full.Append("commitments", commitments...)
+	alpha := full.Challenge("alpha")
+	full.Append("evaluations", evaluations...)
+	want := full.Challenge("beta")
+	
+	// Split before each operation, and after the last one
+	for split := 0; split <= 3; split++ {
+		tr := NewTranscript("poseidon2-test")
+		reload := func() {
+			data, err := tr.MarshalBinary()
+			if err != nil {
+				t.Fatalf("split %d: MarshalBinary failed: %v", split, err)
+			}
+			tr = &Transcript{}
+			if err := tr.UnmarshalBinary(data); err != nil {
+				t.Fatalf("split %d: UnmarshalBinary failed: %v", split, err)
+			}
+		}
+		steps := []func(){
+			func() { tr.Append("commitments", commitments...) },
+			func() {
+				if got := tr.Challenge("alpha"); !got.Equal(&alpha) {
+					t.Errorf("split %d: alpha differs", split)
+				}
+			},
+			func() { tr.Append("evaluations", evaluations...) },
+		}
+		for i, step := range steps {
+			if i == split {
+				reload()
+			}
+			step()
+		}
+		if split == len(steps) {
+			reload()
+		}
+		if got := tr.Challenge("beta"); !got.Equal(&want) {
+			t.Errorf("split %d: final challenge differs from the uninterrupted run", split)
+		}
+	}
+	
+	// Challenges depend on labels and history
+	other := NewTranscript("poseidon2-test")
+	other.Append("commitments", commitments[:2]...)
+	if got := other.Challenge("alpha"); got.Equal(&alpha) {
+		t.Error("challenge does not depend on the appended messages")
+	}
+	
+	// Malformed encodings are rejected without touching the receiver
+	data, _ := NewTranscript("x").MarshalBinary()
+	tr := NewTranscript("y")
+	before, _ := tr.MarshalBinary()
+	bad := map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"version":   append([]byte{99}, data[1:]...),
+		"state":     append(append([]byte{data[0]}, bytes.Repeat([]byte{0xFF}, 32)...), data[33:]...),
+	}
+	for name, encoding := range bad {
+		if err := tr.UnmarshalBinary(encoding); err == nil {
+			t.Errorf("%s encoding was accepted", name)
+		}
+	}
+	if after, _ := tr.MarshalBinary(); !bytes.Equal(after, before) {
+		t.Error("failed UnmarshalBinary modified the transcript")
+	}
+}
+
+// TestIsOneIsMinusOne checks the identity predicates
+func TestIsOneIsMinusOne(t *testing.T) {
+	rMinusOne := new(big.Int).Sub(limbsToBigInt(&rModulus), big.NewInt(1))
+	one, zero, minusOne := One(), Zero(), FromBigInt(rMinusOne)
+	two := FromUint64(2)
+	var negOne Fr
+	negOne.Neg(&one)
+	
+	cases := []struct {
+		name          string
+		f             Fr
+		isOne, isNeg1 bool
+	}{
+		{"One()", one, true, false},
+		{"FromUint64(1)", FromUint64(1), true, false},
+		{"Zero()", zero, false, false},
+		{"FromBigInt(r-1)", minusOne, false, true},
+		{"Neg(One())", negOne, false, true},
+		{"2", two, false, false},
+		{"raw limbs 1", Fr{1, 0, 0, 0}, false, false},
+	}
+	for _, c := range cases {
+		if got := c.f.IsOne(); got != c.isOne {
+			t.Errorf("%s.IsOne() = %v, want %v", c.name, got, c.isOne)
+		}
+		if got := c.f.IsMinusOne(); got != c.isNeg1 {
+			t.Errorf("%s.IsMinusOne() = %v, want %v", c.name, got, c.isNeg1)
+		}
+	}
+}
+
+// TestIsMDS checks the production matrix is MDS and that singular minors are detected
+func TestIsMDS(t *testing.T) {
+	if !IsMDS(mdsMatrix) {
+		t.Fatal("the permutation's mixing matrix is not MDS")
+	}
+	
+	// A zero entry is a singular 1x1 minor
+	m := mdsMatrix
+	m[1][2] = Zero()
+	if IsMDS(m) {
+		t.Error("matrix with a zero entry reported as MDS")
+	}
+	
+	// Two proportional rows on columns {0, 1} make a singular 2x2 minor
+	m = mdsMatrix
+	two := FromUint64(2)
+	m[2][0].Mul(&m[0][0], &two)
+	m[2][1].Mul(&m[0][1], &two)
+	if IsMDS(m) {
+		t.Error("matrix with a singular 2x2 minor reported as MDS")
+	}
+	
+	// The all-ones matrix has nonzero entries but singular 2x2 minors
+	var ones [T][T]Fr
+	for i := range ones {
+		for j := range ones[i] {
+			ones[i][j] = One()
+		}
+	}
+	if IsMDS(ones) {
+		t.Error("all-ones matrix reported as MDS")
+	}
+	
+	// A Cauchy matrix 1/(x_i + y_j) with distinct x_i, distinct y_j and no zero sum is always MDS
+	var cauchy [T][T]Fr
+	for i := 0; i < T; i++ {
+		for j := 0; j < T; j++ {
+			s := FromUint64(uint64(i + T + j + 1)) // x_i = i, y_j = T + j + 1
+			cauchy[i][j].Inverse(&s)
+		}
+	}
+	if !IsMDS(cauchy) {
+		t.Error("Cauchy matrix reported as not MDS")
+	}
+}
+
+// ratToFr maps a rational p/q to p * q^-1 mod r
+func ratToFr(x *big.Rat) Fr {
+	modulus := limbsToBigInt(&rModulus)
+	num := new(big.Int).Mod(x.Num(), modulus)
+	den := new(big.Int).ModInverse(new(big.Int).Mod(x.Denom(), modulus), modulus)
+	return FromBigInt(num.Mul(num, den))
+}
+
+// ratDeterminant computes the determinant over the rationals by cofactor expansion
+func ratDeterminant(m [][]*big.Rat) *big.Rat {
+	if len(m) == 1 {
+		return new(big.Rat).Set(m[0][0])
+	}
+	det := new(big.Rat)
+	for j := range m {
+		minor := make([][]*big.Rat, 0, len(m)-1)
+		for _, row := range m[1:] {
+			minor = append(minor, append(append([]*big.Rat(nil), row[:j]...), row[j+1:]...))
+		}
+		term := new(big.Rat).Mul(m[0][j], ratDeterminant(minor))
+		if j%2 == 1 {
+			term.Neg(term)
+		}
+		det.Add(det, term)
+	}
+	return det
+}
+
+// TestDeterminantAndInverse cross-checks 2x2 and 3x3 matrices against big.Rat arithmetic reduced mod r
+func TestDeterminantAndInverse(t *testing.T) {
+	rng := rand.New(rand.NewSource(93))
+	for trial := 0; trial < 50; trial++ {
+		n := 2 + trial%2
+		ints := make([][]*big.Rat, n)
+		m := make([][]Fr, n)
+		for i := range ints {
+			ints[i] = make([]*big.Rat, n)
+			m[i] = make([]Fr, n)
+			for j := range ints[i] {
+				v := rng.Int63n(2001) - 1000
+				ints[i][j] = big.NewRat(v, 1)
+				m[i][j] = FromInt64(v)
+			}
+		}
+		
+		ratDet := ratDeterminant(ints)
+		det, err := Determinant(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := ratToFr(ratDet); !det.Equal(&want) {
+			t.Fatalf("trial %d: Determinant = %s, want %s", trial, det.String(), ratDet.String())
+		}
+		
+		inv, err := MatrixInverse(m)
+		if ratDet.Sign() == 0 {
+			if err == nil {
+				t.Errorf("trial %d: singular matrix was inverted", trial)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("trial %d: MatrixInverse failed: %v", trial, err)
+		}
+		
+		// inverse[i][j] = (-1)^(i+j) * minor(j, i) / det
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				var minor [][]*big.Rat
+				for r := 0; r < n; r++ {
+					if r == j {
+						continue
+					}
+					var row []*big.Rat
+					for c := 0; c < n; c++ {
+						if c != i {
+							row = append(row, ints[r][c])
+						}
+					}
+					minor = append(minor, row)
+				}
+				entry := new(big.Rat).Quo(ratDeterminant(minor), ratDet)
+				if (i+j)%2 == 1 {
+					entry.Neg(entry)
+				}
+				if want := ratToFr(entry); !inv[i][j].Equal(&want) {
+					t.Fatalf("trial %d: inverse[%d][%d] = %s, want %s", trial, i, j, inv[i][j].String(), entry.String())
+				}
+			}
+		}
+	}
+	
+	singular := [][]Fr{{FromUint64(1), FromUint64(2)}, {FromUint64(2), FromUint64(4)}}
+	if det, _ := Determinant(singular); !det.IsZero() {
+		t.Error("determinant of a singular matrix should be zero")
+	}
+	if _, err := MatrixInverse(singular); err == nil {
+		t.Error("MatrixInverse should reject a singular matrix")
+	}
+	
+	// A zero leading entry needs a row swap, which flips the sign
+	swapped := [][]Fr{{Zero(), One()}, {One(), Zero()}}
+	if det, _ := Determinant(swapped); !det.IsMinusOne() {
+		t.Errorf("det([[0 1] [1 0]]) = %s, want -1", det.String())
+	}
+	
+	for _, bad := range [][][]Fr{nil, {{One(), One()}}, {{One()}, {One(), One()}}} {
+		if _, err := Determinant(bad); err == nil {
+			t.Errorf("Determinant(%v) should fail", bad)
+		}
+		if _, err := MatrixInverse(bad); err == nil {
+			t.Errorf("MatrixInverse(%v) should fail", bad)
+		}
+	}
+}
+
+// TestHashTwoFast checks the one-shot hash matches Compress2 and the sponge
+func TestHashTwoFast(t *testing.T) {
+	rng := rand.New(rand.NewSource(94))
+	for i := 0; i < 20; i++ {
+		a, b := randomFr(rng), randomFr(rng)
+		got := HashTwoFast(a, b)
+		for name, want := range map[string]Fr{
+			"Compress2": Compress2(a, b),
+			"Hash":      Hash(a, b),
+			"Hash2":     Hash2(a, b),
+		} {
+			if !got.Equal(&want) {
+				t.Fatalf("HashTwoFast differs from %s", name)
+			}
+		}
+	}
+}
+
+// BenchmarkHashTwoFast benchmarks the one-shot two-element hash; compare BenchmarkCompress2 and BenchmarkHash2
+func BenchmarkHashTwoFast(b *testing.B) {
+	x, y := benchOperands()
+	
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		x = HashTwoFast(x, y)
+	}
+	benchSinkFr = x
+}
+
+// TestCmp checks Cmp orders by canonical value rather than Montgomery limbs
+func TestCmp(t *testing.T) {
+	rMinusOne := FromBigInt(new(big.Int).Sub(limbsToBigInt(&rModulus), big.NewInt(1)))
+	ordered := []Fr{Zero(), One(), FromUint64(2), FromUint64(1 << 40), rMinusOne}
+	for i := range ordered {
+		for j := range ordered {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := ordered[i].Cmp(&ordered[j]); got != want {
+				t.Errorf("Cmp(%d, %d) = %d, want %d", i, j, got, want)
+			}
+		}
+	}
+	
+	rng := rand.New(rand.NewSource(96))
+	for i := 0; i < 1000; i++ {
+		a, b := randomFr(rng), randomFr(rng)
+		if got, want := a.Cmp(&b), a.ToBigInt().Cmp(b.ToBigInt()); got != want {
+			t.Fatalf("Cmp = %d, big.Int comparison gives %d", got, want)
+		}
+	}
+}
+
+// TestHashSet checks order independence and that duplicates still count
+func TestHashSet(t *testing.T) {
+	a, b, c := FromUint64(1), FromUint64(2), FromUint64(3)
+	
+	ab := HashSet(DomainGeneric, []Fr{a, b})
+	if ba := HashSet(DomainGeneric, []Fr{b, a}); !ab.Equal(&ba) {
+		t.Error("HashSet(a, b) != HashSet(b, a)")
+	}
+	if aa := HashSet(DomainGeneric, []Fr{a, a}); ab.Equal(&aa) {
+		t.Error("HashSet(a, b) == HashSet(a, a)")
+	}
+	if single := HashSet(DomainGeneric, []Fr{a}); single.Equal(&ab) {
+		t.Error("HashSet(a) == HashSet(a, b)")
+	}
+	if aa, single := HashSet(DomainGeneric, []Fr{a, a}), HashSet(DomainGeneric, []Fr{a}); aa.Equal(&single) {
+		t.Error("duplicates should change HashSet")
+	}
+	if other := HashSet(DomainPOETNode, []Fr{a, b}); other.Equal(&ab) {
+		t.Error("HashSet ignores the domain tag")
+	}
+	
+	// A sorted set is not an ordered sequence of the same values
+	if seq := HashVarUints(DomainGeneric, []uint64{1, 2}); seq.Equal(&ab) {
+		t.Error("HashSet collides with HashVarUints")
+	}
+	if seq := HashMany(DomainGeneric, FromUint64(2), a, b); seq.Equal(&ab) {
+		t.Error("HashSet collides with HashMany of tag, count and elements")
+	}
+	iv := FromUint64(uint64(domainSet))
+	if want := HashWithIV(iv, FromUint64(uint64(DomainGeneric)), FromUint64(2), a, b); !ab.Equal(&want) {
+		t.Error("HashSet should seed the capacity with domainSet and absorb tag, count and elements")
+	}
+	if tag, ok := LookupDomain("set"); !ok || tag != domainSet {
+		t.Error("domainSet is not registered")
+	}
+	
+	// Every ordering of three elements agrees, and the input is not reordered
+	want := HashSet(DomainGeneric, []Fr{a, b, c})
+	for _, perm := range [][]Fr{{a, c, b}, {b, a, c}, {b, c, a}, {c, a, b}, {c, b, a}} {
+		original := append([]Fr(nil), perm...)
+		if got := HashSet(DomainGeneric, perm); !got.Equal(&want) {
+			t.Error("HashSet depends on element order")
+		}
+		if !EqualSlices(perm, original) {
+			t.Error("HashSet reordered its input slice")
+		}
+	}
+}
+
+// TestMultisetHash checks order independence, multiplicity and incremental updates
+func TestMultisetHash(t *testing.T) {
+	a, b, c := FromUint64(1), FromUint64(2), FromUint64(3)
+	
+	abc := MultisetHash([]Fr{a, b, c})
+	for _, perm := range [][]Fr{{a, c, b}, {b, a, c}, {c, b, a}} {
+		if got := MultisetHash(perm); !got.Equal(&abc) {
+			t.Error("MultisetHash depends on element order")
+		}
+	}
+	if empty := MultisetHash(nil); !empty.IsOne() {
+		t.Error("empty multiset should hash to One()")
+	}
+	if aab, ab := MultisetHash([]Fr{a, a, b}), MultisetHash([]Fr{a, b}); aab.Equal(&ab) {
+		t.Error("multiplicity should change MultisetHash")
+	}
+	
+	// Incremental updates agree with hashing from scratch
+	acc := MultisetHash([]Fr{a, b})
+	acc = MultisetAdd(acc, c)
+	if !acc.Equal(&abc) {
+		t.Error("MultisetAdd differs from MultisetHash of the extended multiset")
+	}
+	
+	// Adding then removing restores the accumulator
+	rng := rand.New(rand.NewSource(97))
+	for i := 0; i < 10; i++ {
+		x := randomFr(rng)
+		if got := MultisetRemove(MultisetAdd(abc, x), x); !got.Equal(&abc) {
+			t.Error("adding then removing an element did not restore the accumulator")
+		}
+	}
+	if got, want := MultisetRemove(abc, b), MultisetHash([]Fr{c, a}); !got.Equal(&want) {
+		t.Error("removing an element differs from hashing the remaining multiset")
+	}
+}
+
+// TestHasherCapacity checks Capacity on fresh, partially absorbed and permuted hashers
+func TestHasherCapacity(t *testing.T) {
+	h := NewHasher()
+	if c := h.Capacity(); !c.IsZero() {
+		t.Error("fresh hasher should have zero capacity")
+	}
+	iv := FromUint64(7)
+	if c := NewHasherWithIV(iv).Capacity(); !c.Equal(&iv) {
+		t.Error("capacity of NewHasherWithIV should be the IV")
+	}
+	
+	// A pending element stays in the rate until the block is permuted
+	h.Absorb(FromUint64(1))
+	if c := h.Capacity(); !c.IsZero() {
+		t.Error("capacity changed before any permutation")
+	}
+	h.Absorb(FromUint64(2))
+	want := PermuteCopy([T]Fr{FromUint64(1), FromUint64(2), Zero()})
+	if c := h.Capacity(); c.IsZero() || !c.Equal(&want[T-1]) {
+		t.Error("capacity after a permutation should be the permuted state[T-1]")
+	}
+	if state := h.State(); !state[T-1].Equal(&want[T-1]) {
+		t.Error("Capacity disagrees with State")
+	}
+}
+
+// TestDuplex checks the duplex construction, determinism and sensitivity to call order
+func TestDuplex(t *testing.T) {
+	tag := DomainFSChallenge
+	a, b := FromUint64(1), FromUint64(2)
+	
+	// The documented construction for a single absorb block and squeeze
+	state := PermuteCopy([T]Fr{Zero(), Zero(), FromUint64(uint64(tag))})
+	start := FromUint64(2)
+	state[0].Add(&state[0], &a)
+	state[1].Add(&state[1], &b)
+	state[T-1].Add(&state[T-1], &start)
+	ProductionPermutation(&state)
+	frame := FromUint64(T)
+	state[T-1].Add(&state[T-1], &frame)
+	ProductionPermutation(&state)
+	d := NewDuplex(tag)
+	d.Absorb([]Fr{a, b})
+	if got := d.Squeeze(2); !got[0].Equal(&state[0]) || !got[1].Equal(&state[1]) {
+		t.Error("Duplex does not follow the documented construction")
+	}
+	
+	run := func(steps func(d *Duplex) []Fr) []Fr {
+		return steps(NewDuplex(tag))
+	}
+	interleaved := func(d *Duplex) []Fr {
+		d.Absorb([]Fr{a})
+		out := d.Squeeze(3)
+		d.Absorb([]Fr{b, a, b})
+		return append(out, d.Squeeze(1)...)
+	}
+	first, second := run(interleaved), run(interleaved)
+	if len(first) != 4 {
+		t.Fatalf("got %d outputs, want 4", len(first))
+	}
+	for i := range first {
+		if !first[i].Equal(&second[i]) {
+			t.Fatal("identical duplex sequences produced different output")
+		}
+	}
+	
+	variants := map[string]func(d *Duplex) []Fr{
+		"swapped elements": func(d *Duplex) []Fr {
+			d.Absorb([]Fr{b, a})
+			return d.Squeeze(1)
+		},
+		"zero appended": func(d *Duplex) []Fr {
+			d.Absorb([]Fr{a, b, Zero()})
+			return d.Squeeze(1)
+		},
+		"split absorb": func(d *Duplex) []Fr {
+			d.Absorb([]Fr{a})
+			d.Absorb([]Fr{b})
+			return d.Squeeze(1)
+		},
+		"squeeze first": func(d *Duplex) []Fr {
+			d.Squeeze(1)
+			d.Absorb([]Fr{a, b})
+			return d.Squeeze(1)
+		},
+		"empty squeeze": func(d *Duplex) []Fr {
+			d.Absorb([]Fr{a, b})
+			d.Squeeze(0)
+			return d.Squeeze(1)
+		},
+	}
+	base := run(func(d *Duplex) []Fr {
+		d.Absorb([]Fr{a, b})
+		return d.Squeeze(1)
+	})
+	for name, steps := range variants {
+		if got := run(steps); got[0].Equal(&base[0]) {
+			t.Errorf("%s: reordering or reframing did not change the output", name)
+		}
+	}
+	
+	// A full block inside one call is framed apart from a full block ending a call
+	c := FromUint64(3)
+	oneCall := run(func(d *Duplex) []Fr {
+		d.Absorb([]Fr{a, b, c})
+		return d.Squeeze(1)
+	})
+	twoCalls := run(func(d *Duplex) []Fr {
+		d.Absorb([]Fr{a, b})
+		d.Absorb([]Fr{c})
+		return d.Squeeze(1)
+	})
+	if oneCall[0].Equal(&twoCalls[0]) {
+		t.Error("Absorb([a, b, c]) collides with Absorb([a, b]); Absorb([c])")
+	}
+	
+	other := NewDuplex(DomainMerkleNode)
+	other.Absorb([]Fr{a, b})
+	if got := other.Squeeze(1); got[0].Equal(&base[0]) {
+		t.Error("different tags produced the same output")
+	}
+	
+	// Frame values must not carry one tag onto an adjacent one: tag+2 after a full
+	// zero block would equal tag'+0 after an empty block if tags were not permuted first
+	for _, tags := range [][2]Domain{{DomainMerkleLeaf, DomainMerkleNode}, {DomainGeneric, DomainGeneric + 1}, {DomainGeneric, DomainGeneric + 3}} {
+		lo, hi := NewDuplex(tags[0]), NewDuplex(tags[1])
+		lo.Absorb([]Fr{Zero(), Zero()})
+		hi.Absorb(nil)
+		if x, y := lo.Squeeze(2), hi.Squeeze(2); x[0].Equal(&y[0]) || x[1].Equal(&y[1]) {
+			t.Errorf("tags %#x and %#x collide through the capacity frame", tags[0], tags[1])
+		}
+		
+		lo, hi = NewDuplex(tags[0]), NewDuplex(tags[1])
+		if x, y := lo.Squeeze(1), hi.Squeeze(1); x[0].Equal(&y[0]) {
+			t.Errorf("tags %#x and %#x collide on the first squeeze", tags[0], tags[1])
+		}
+	}
+}
+
+// TestHashToBytes checks HashToBytes against Hash and the fixed empty hash
+func TestHashToBytes(t *testing.T) {
+	rng := rand.New(rand.NewSource(904))
+	for n := 0; n <= 5; n++ {
+		elements := make([]Fr, n)
+		for i := range elements {
+			elements[i] = randomFr(rng)
+		}
+		want := Hash(elements...)
+		if got := HashToBytes(elements...); got != want.ToBytes32() {
+			t.Errorf("n=%d: HashToBytes differs from Hash(...).ToBytes32()", n)
+		}
+	}
+	
+	empty := PermuteCopy([T]Fr{})
+	got := HashToBytes()
+	if got != empty[0].ToBytes32() {
+		t.Error("HashToBytes() should be the bytes of Perm(0, 0, 0)[0]")
+	}
+	if got == ([32]byte{}) {
+		t.Error("HashToBytes() should not be all zero")
+	}
+}
+
+// TestSqueezeInterleaving checks Squeeze and SqueezeN share one output stream
+func TestSqueezeInterleaving(t *testing.T) {
+	input := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	
+	ref := NewHasher()
+	ref.AbsorbMany(input)
+	want := ref.SqueezeN(5)
+	
+	h := NewHasher()
+	h.AbsorbMany(input)
+	got := []Fr{h.Squeeze()}
+	got = append(got, h.SqueezeN(2)...)
+	got = append(got, h.Squeeze(), h.Squeeze())
+	for i := range want {
+		if !got[i].Equal(&want[i]) {
+			t.Fatalf("output %d of interleaved Squeeze/SqueezeN differs from SqueezeN(5)", i)
+		}
+	}
+	if want[0].Equal(&want[1]) {
+		t.Error("consecutive outputs should differ")
+	}
+	
+	// Repeated Squeeze calls walk the stream rather than repeating state[0]
+	twice := NewHasher()
+	twice.AbsorbMany(input)
+	if s0, s1 := twice.Squeeze(), twice.Squeeze(); !s0.Equal(&want[0]) || !s1.Equal(&want[1]) {
+		t.Error("Squeeze(); Squeeze() differs from SqueezeN(2)")
+	}
+	
+	// A negative count returns nothing but still permutes pending input, like SqueezeN(0)
+	neg, zero := NewHasher(), NewHasher()
+	neg.AbsorbMany(input)
+	zero.AbsorbMany(input)
+	if out := neg.SqueezeN(-1); len(out) != 0 {
+		t.Errorf("SqueezeN(-1) returned %d elements, want 0", len(out))
+	}
+	zero.SqueezeN(0)
+	if neg.State() != zero.State() {
+		t.Error("SqueezeN(-1) should behave like SqueezeN(0)")
+	}
+	if got := neg.Squeeze(); !got.Equal(&want[0]) {
+		t.Error("stream after SqueezeN(-1) should start at the first output")
+	}
+	
+	// Absorbing restarts the stream at state[0] of the new permutation
+	h.Absorb(FromUint64(4))
+	first := h.Squeeze()
+	if state := h.State(); !first.Equal(&state[0]) {
+		t.Error("Squeeze after absorbing should return state[0]")
+	}
+}
+
+// TestHasherResetKeepsIV checks Reset restores the IV of NewHasherWithIV
+func TestHasherResetKeepsIV(t *testing.T) {
+	iv := FromUint64(uint64(DomainFSChallenge))
+	input := []Fr{FromUint64(1), FromUint64(2), FromUint64(3)}
+	want := HashWithIV(iv, input...)
+	
+	h := NewHasherWithIV(iv)
+	h.AbsorbMany([]Fr{FromUint64(9), FromUint64(8), FromUint64(7)})
+	h.Finalize()
+	h.Reset()
+	if c := h.Capacity(); !c.Equal(&iv) {
+		t.Fatal("Reset dropped the IV from the capacity")
+	}
+	h.AbsorbMany(input)
+	if got := h.Finalize(); !got.Equal(&want) {
+		t.Error("hash after Reset differs from a fresh NewHasherWithIV")
+	}
+	if plain := Hash(input...); want.Equal(&plain) {
+		t.Error("IV hasher should differ from plain Hash")
+	}
+	
+	// A plain hasher still resets to a zero capacity
+	plain := NewHasher()
+	plain.Absorb(One())
+	plain.Reset()
+	if c := plain.Capacity(); !c.IsZero() {
+		t.Error("Reset of NewHasher should leave a zero capacity")
+	}
+}
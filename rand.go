@@ -0,0 +1,44 @@
+package poseidon2
+
+// domainRand separates the generator's permutation inputs from every hashing mode
+const domainRand Domain = 0x5347524e // "SGRN"
+
+// Poseidon2Rand is a deterministic generator running the permutation in counter mode
+// Output i is state[0] of ProductionPermutation([seed, i, domainRand]); the other
+// state elements are discarded so the permutation cannot be inverted from outputs.
+// It is reproducible, not a replacement for crypto/rand, and is not safe for concurrent use
+type Poseidon2Rand struct {
+	seed    Fr
+	counter uint64
+	buf     []byte // Unread bytes from the last element consumed by Read
+}
+
+// NewPoseidon2Rand creates a generator whose output stream is fixed by seed
+func NewPoseidon2Rand(seed Fr) *Poseidon2Rand {
+	return &Poseidon2Rand{seed: seed}
+}
+
+// NextFr returns the next field element of the stream
+func (r *Poseidon2Rand) NextFr() Fr {
+	state := [T]Fr{r.seed, FromUint64(r.counter), FromUint64(uint64(domainRand))}
+	ProductionPermutation(&state)
+	r.counter++
+	return state[0]
+}
+
+// Read fills p with pseudorandom bytes and never fails, implementing io.Reader
+// Each element contributes only its low 16 bytes, which are within 2^-125 of
+// uniform; the upper bits of a value below r are too biased to emit
+func (r *Poseidon2Rand) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			b := r.NextFr().ToBytes32()
+			r.buf = b[16:]
+		}
+		copied := copy(p[n:], r.buf)
+		r.buf = r.buf[copied:]
+		n += copied
+	}
+	return n, nil
+}
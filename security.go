@@ -0,0 +1,81 @@
+package poseidon2
+
+import (
+	"fmt"
+	"math"
+)
+
+// SecurityLevel is the target security in bits used by ValidateSecurityParameters
+const SecurityLevel = 128
+
+// ValidateSecurityParameters checks a Poseidon2 configuration against the round-number
+// lower bounds of the Poseidon/Poseidon2 papers for an x^d S-box at SecurityLevel bits
+// The bounds follow the reference calc_round_numbers script: statistical, interpolation
+// and three Groebner-basis attacks, plus the binomial bound of ePrint 2023/537.
+// They are the raw bounds without the recommended margin (rF+2, rP*1.075), so a
+// configuration that only just passes should add the margin before use.
+// gcd(d, r-1) = 1 cannot be checked from the bit length and is the caller's responsibility
+func ValidateSecurityParameters(t, d, rF, rP int, modulusBits int) error {
+	if t < 2 {
+		return fmt.Errorf("state width t=%d must be at least 2", t)
+	}
+	if d < 3 || d%2 == 0 {
+		return fmt.Errorf("S-box degree d=%d must be an odd integer >= 3", d)
+	}
+	if rF <= 0 || rF%2 != 0 {
+		return fmt.Errorf("full rounds rF=%d must be positive and even", rF)
+	}
+	if rP < 0 {
+		return fmt.Errorf("partial rounds rP=%d cannot be negative", rP)
+	}
+	if modulusBits < 32 {
+		return fmt.Errorf("modulus of %d bits is too small", modulusBits)
+	}
+	
+	m := float64(SecurityLevel)
+	n := float64(modulusBits)          // ceil(log2 p)
+	logP := float64(modulusBits - 1)   // floor(log2 p)
+	log2Alpha := math.Log2(float64(d)) // log2(d)
+	logAlpha2 := 1 / log2Alpha         // log_d(2)
+	tf := float64(t)
+	rPf := float64(rP)
+	
+	// Statistical attacks
+	statistical := 10.0
+	if m <= (logP-(float64(d)-1)/2)*(tf+1) {
+		statistical = 6
+	}
+	bounds := []struct {
+		name string
+		rF   float64
+	}{
+		{"statistical", statistical},
+		{"interpolation", 1 + math.Ceil(logAlpha2*math.Min(m, n)) + math.Ceil(math.Log(tf)/math.Log(float64(d))) - rPf},
+		{"Groebner 1", logAlpha2*math.Min(m, logP) - rPf},
+		{"Groebner 2", tf - 1 + logAlpha2*math.Min(m/(tf+1), logP/2) - rPf},
+		{"Groebner 3", (tf - 2 + m/(2*log2Alpha) - rPf) / (tf - 1)},
+	}
+	for _, b := range bounds {
+		if float64(rF) < math.Ceil(b.rF) {
+			return fmt.Errorf("%s attack requires rF >= %d with rP=%d, got rF=%d", b.name, int(math.Ceil(b.rF)), rP, rF)
+		}
+	}
+	
+	// Groebner basis bound from ePrint 2023/537
+	rTemp := math.Floor(tf / 3)
+	over := float64(rF-1)*tf + rPf + rTemp + rTemp*float64(rF)/2 + rPf + float64(d)
+	under := rTemp*float64(rF)/2 + rPf + float64(d)
+	if cost := math.Ceil(2 * log2Binomial(over, under)); cost < m {
+		return fmt.Errorf("Groebner basis attack costs 2^%d < 2^%d", int(cost), SecurityLevel)
+	}
+	
+	return nil
+}
+
+// log2Binomial returns log2 of the (generalized) binomial coefficient C(n, k)
+func log2Binomial(n, k float64) float64 {
+	lgN, _ := math.Lgamma(n + 1)
+	lgK, _ := math.Lgamma(k + 1)
+	lgNK, _ := math.Lgamma(n - k + 1)
+	return (lgN - lgK - lgNK) / math.Ln2
+}
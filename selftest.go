@@ -0,0 +1,56 @@
+package poseidon2
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// SelfTest verifies the Montgomery constants and conversions at runtime
+// Security-critical deployments can call it at startup; a non-nil error means
+// field arithmetic is corrupt and no hash output can be trusted
+func SelfTest() error {
+	modulus := limbsToBigInt(&rModulus)
+	
+	// nPrime must satisfy r * nPrime == -1 mod 2^64
+	if rModulus[0]*nPrime != ^uint64(0) {
+		return fmt.Errorf("self-test: nPrime 0x%016x is not -r^-1 mod 2^64", uint64(nPrime))
+	}
+	
+	// R = 2^256 mod r and R2 = 2^512 mod r
+	wantR := new(big.Int).Lsh(big.NewInt(1), 256)
+	wantR.Mod(wantR, modulus)
+	if limbsToBigInt(&montgomeryR).Cmp(wantR) != 0 {
+		return fmt.Errorf("self-test: montgomeryR is not 2^256 mod r")
+	}
+	wantR2 := new(big.Int).Lsh(big.NewInt(1), 512)
+	wantR2.Mod(wantR2, modulus)
+	if limbsToBigInt(&montgomeryR2).Cmp(wantR2) != 0 {
+		return fmt.Errorf("self-test: montgomeryR2 is not 2^512 mod r")
+	}
+	
+	// One() is the multiplicative identity
+	one := One()
+	var product Fr
+	product.Mul(&one, &one)
+	if !product.Equal(&one) {
+		return fmt.Errorf("self-test: One()*One() != One()")
+	}
+	
+	// Conversions round-trip and multiplication agrees with big.Int
+	for _, x := range []uint64{2, 3, 12345, 0xFEDCBA0987654321, ^uint64(0)} {
+		fx := FromUint64(x)
+		if fx.ToBigInt().Cmp(new(big.Int).SetUint64(x)) != 0 {
+			return fmt.Errorf("self-test: FromUint64(%d) does not round-trip", x)
+		}
+		
+		product.Mul(&fx, &fx)
+		want := new(big.Int).SetUint64(x)
+		want.Mul(want, want)
+		want.Mod(want, modulus)
+		if product.ToBigInt().Cmp(want) != 0 {
+			return fmt.Errorf("self-test: %d*%d disagrees with big.Int", x, x)
+		}
+	}
+	
+	return nil
+}
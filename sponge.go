@@ -1,10 +1,20 @@
 package poseidon2
 
+import "fmt"
+
+// defaultRate is the sponge rate used for both absorbing and squeezing unless configured otherwise
+const defaultRate = T - 1
+
 // Hasher represents the Poseidon2 sponge state for production use
 // For t=3: rate=2, capacity=1
 type Hasher struct {
-	state    [T]Fr // Sponge state
-	absorbed int   // Number of elements absorbed in current block
+	state       [T]Fr // Sponge state
+	absorbed    int   // Number of elements absorbed in current block
+	squeezed    int   // Number of rate elements already output by Squeeze/SqueezeN from current block
+	permuted    bool  // Whether the state has been permuted at least once
+	absorbRate  int   // Elements absorbed per permutation; 0 means defaultRate
+	squeezeRate int   // Elements output by SqueezeN per permutation; 0 means defaultRate
+	iv          Fr    // Initial capacity element, restored by Reset
 }
 
 // NewHasher creates a new Poseidon2 hasher instance
@@ -15,15 +25,60 @@ func NewHasher() *Hasher {
 	}
 }
 
+// NewHasherWithIV creates a hasher whose capacity element starts at iv instead of zero
+// A zero IV is identical to NewHasher; Reset returns the capacity to iv
+func NewHasherWithIV(iv Fr) *Hasher {
+	h := NewHasher()
+	h.state[T-1] = iv
+	h.iv = iv
+	return h
+}
+
+// NewHasherRates creates a hasher that absorbs absorbRate elements and squeezes
+// squeezeRate elements per permutation. Both rates must lie in [1, T-1];
+// NewHasherRates(2, 2) is identical to NewHasher. A smaller squeeze rate trades
+// throughput for output elements that are never adjacent in one permuted state
+func NewHasherRates(absorbRate, squeezeRate int) (*Hasher, error) {
+	if absorbRate < 1 || absorbRate >= T {
+		return nil, fmt.Errorf("absorb rate %d out of range [1, %d]", absorbRate, T-1)
+	}
+	if squeezeRate < 1 || squeezeRate >= T {
+		return nil, fmt.Errorf("squeeze rate %d out of range [1, %d]", squeezeRate, T-1)
+	}
+	h := NewHasher()
+	h.absorbRate = absorbRate
+	h.squeezeRate = squeezeRate
+	return h, nil
+}
+
+// rates returns the effective absorb and squeeze rates of h
+func (h *Hasher) rates() (absorb, squeeze int) {
+	absorb, squeeze = h.absorbRate, h.squeezeRate
+	if absorb == 0 {
+		absorb = defaultRate
+	}
+	if squeeze == 0 {
+		squeeze = defaultRate
+	}
+	return absorb, squeeze
+}
+
+// permute applies the production permutation to the sponge state
+func (h *Hasher) permute() {
+	ProductionPermutation(&h.state)
+	h.permuted = true
+}
+
 // Absorb absorbs a single field element into the sponge
 func (h *Hasher) Absorb(element Fr) {
 	// Add element to the appropriate position in the rate portion
 	h.state[h.absorbed].Add(&h.state[h.absorbed], &element)
 	h.absorbed++
+	h.squeezed = 0
 	
 	// If rate is full, apply permutation and reset
-	if h.absorbed >= 2 { // rate = 2 for t=3
-		ProductionPermutation(&h.state)
+	if absorbRate, _ := h.rates(); h.absorbed >= absorbRate {
+		h.permute()
 		h.absorbed = 0
 	}
 }
@@ -35,32 +90,118 @@ func (h *Hasher) AbsorbMany(elements []Fr) {
 	}
 }
 
-// Squeeze extracts one field element from the sponge
-// Applies permutation if no elements have been absorbed since last squeeze
+// Squeeze extracts the next field element of the sponge's output stream
+// It is SqueezeN(1)[0] without the allocation and shares SqueezeN's position, so
+// the two may be interleaved: Squeeze then SqueezeN(2) outputs the same three
+// elements as SqueezeN(3). After absorbing, the first output is state[0].
+// Repeated calls advance the stream (Squeeze(); Squeeze() equals SqueezeN(2));
+// earlier versions returned state[0] again on every call without new input
 func (h *Hasher) Squeeze() Fr {
-	// If we've absorbed something since last permutation, apply it now
-	if h.absorbed > 0 {
-		ProductionPermutation(&h.state)
+	h.startSqueeze()
+	return h.nextOutput()
+}
+
+// SqueezeN extracts n field elements from the sponge
+// Pending input is permuted in first, then rate elements are output in order,
+// applying a permutation whenever the rate portion is exhausted. Successive
+// calls (of SqueezeN or Squeeze) continue the same output stream; absorbing again
+// restarts it. Only the first squeeze-rate elements of each permuted state are output.
+// A negative n is treated as zero: pending input is still permuted in and an
+// empty slice is returned, as Duplex.Squeeze does
+func (h *Hasher) SqueezeN(n int) []Fr {
+	h.startSqueeze()
+	if n < 0 {
+		n = 0
+	}
+	out := make([]Fr, n)
+	for i := range out {
+		out[i] = h.nextOutput()
+	}
+	return out
+}
+
+// startSqueeze permutes in pending input (or a never-permuted state) and restarts the output stream
+func (h *Hasher) startSqueeze() {
+	if h.absorbed > 0 || !h.permuted {
+		h.permute()
 		h.absorbed = 0
+		h.squeezed = 0
 	}
-	
-	// Return first element of the state (index 0)
-	return h.state[0]
+}
+
+// nextOutput returns the next rate element, permuting when the squeeze rate is exhausted
+func (h *Hasher) nextOutput() Fr {
+	if _, squeezeRate := h.rates(); h.squeezed >= squeezeRate {
+		h.permute()
+		h.squeezed = 0
+	}
+	out := h.state[h.squeezed]
+	h.squeezed++
+	return out
 }
 
 // Finalize completes the sponge absorption and returns the hash
+// A fresh sponge is permuted once, so the empty hash is a pseudorandom constant rather than Zero()
 func (h *Hasher) Finalize() Fr {
 	// Apply final permutation if needed
-	if h.absorbed > 0 {
-		ProductionPermutation(&h.state)
+	if h.absorbed > 0 || !h.permuted {
+		h.permute()
 	}
 	
 	// Return the first element as the hash result
 	return h.state[0]
 }
 
-// Reset resets the hasher to initial state
+// State returns a copy of the current sponge state for debugging
+// Elements absorbed since the last permutation have been added to the rate
+// positions but not yet permuted; see Absorbed
+func (h *Hasher) State() [T]Fr {
+	return h.state
+}
+
+// Absorbed returns how many elements have been absorbed into the current block
+// It is always below the absorb rate: a full block is permuted immediately
+func (h *Hasher) Absorbed() int {
+	return h.absorbed
+}
+
+// Capacity returns the capacity element state[T-1], which no output exposes
+// It is the IV on a fresh hasher (zero for NewHasher) and changes with every
+// permutation; pending input only touches the rate, so it does not affect Capacity
+// until its block is permuted. Together with State it lets a caller carry the full
+// sponge into a continued computation
+func (h *Hasher) Capacity() Fr {
+	return h.state[T-1]
+}
+
+// Reset resets the hasher to initial state, keeping its configured rates and IV
 func (h *Hasher) Reset() {
 	h.state = [T]Fr{Zero(), Zero(), Zero()}
+	h.state[T-1] = h.iv
 	h.absorbed = 0
-}
\ No newline at end of file
+	h.squeezed = 0
+	h.permuted = false
+}
+
+// squeezeState returns the state the squeeze phase starts from, without modifying h
+// Pending input is permuted in exactly as Finalize and SqueezeN would
+func (h *Hasher) squeezeState() [T]Fr {
+	state := h.state
+	if h.absorbed > 0 || !h.permuted {
+		ProductionPermutation(&state)
+	}
+	return state
+}
+
+// DetectSpongeCollision reports whether absorbing a and b leaves the sponge in the
+// same state at the start of the squeeze phase, after any pending block has been
+// permuted in. Every output (Finalize, Squeeze, SqueezeN) is a function of that
+// state, so a true result means the two inputs are indistinguishable to the sponge;
+// for example [x] and [x, 0] collide because the sponge has no length padding.
+// It is an analysis aid for validating padding and encoding changes
+func DetectSpongeCollision(a, b []Fr) bool {
+	ha, hb := NewHasher(), NewHasher()
+	ha.AbsorbMany(a)
+	hb.AbsorbMany(b)
+	return ha.squeezeState() == hb.squeezeState()
+}
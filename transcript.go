@@ -0,0 +1,107 @@
+package poseidon2
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Transcript is a Fiat-Shamir transcript over the Poseidon2 sponge
+// The capacity is seeded with DomainFSChallenge and every message and challenge is
+// bound to a label (via LabelToFr), so challenges depend on the full ordered
+// history. A Transcript is not safe for concurrent use
+type Transcript struct {
+	h *Hasher
+}
+
+// NewTranscript starts a transcript for the protocol named by label
+func NewTranscript(label string) *Transcript {
+	h := NewHasherWithIV(FromUint64(uint64(DomainFSChallenge)))
+	h.Absorb(LabelToFr(label))
+	return &Transcript{h: h}
+}
+
+// Append absorbs a labelled message: the label, the element count, then the elements
+func (t *Transcript) Append(label string, elements ...Fr) {
+	t.h.Absorb(LabelToFr(label))
+	t.h.Absorb(FromUint64(uint64(len(elements))))
+	t.h.AbsorbMany(elements)
+}
+
+// Challenge absorbs label and squeezes a challenge from everything appended so far
+// Consecutive challenges differ because each label is absorbed before squeezing
+func (t *Transcript) Challenge(label string) Fr {
+	t.h.Absorb(LabelToFr(label))
+	return t.h.Squeeze()
+}
+
+// transcriptVersion identifies the MarshalBinary layout
+const transcriptVersion = 1
+
+// transcriptEncodedSize is the length of a marshaled transcript: version, the state
+// elements, absorbed and squeezed counters, the permuted flag and the two rates
+const transcriptEncodedSize = 1 + T*32 + 8 + 8 + 1 + 1 + 1
+
+// MarshalBinary implements encoding.BinaryMarshaler
+// The sponge state (as canonical 32-byte big-endian elements), block counters and
+// rates are written, so UnmarshalBinary resumes with identical later challenges
+func (t *Transcript) MarshalBinary() ([]byte, error) {
+	h := t.h
+	out := make([]byte, 0, transcriptEncodedSize)
+	out = append(out, transcriptVersion)
+	for i := range h.state {
+		b := h.state[i].ToBytes32()
+		out = append(out, b[:]...)
+	}
+	out = binary.BigEndian.AppendUint64(out, uint64(h.absorbed))
+	out = binary.BigEndian.AppendUint64(out, uint64(h.squeezed))
+	permuted := byte(0)
+	if h.permuted {
+		permuted = 1
+	}
+	absorbRate, squeezeRate := h.rates()
+	out = append(out, permuted, byte(absorbRate), byte(squeezeRate))
+	return out, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler, restoring a transcript
+// written by MarshalBinary. Malformed input is rejected and leaves t unchanged
+func (t *Transcript) UnmarshalBinary(data []byte) error {
+	if len(data) != transcriptEncodedSize {
+		return fmt.Errorf("transcript encoding has %d bytes, want %d", len(data), transcriptEncodedSize)
+	}
+	if data[0] != transcriptVersion {
+		return fmt.Errorf("unsupported transcript encoding version %d", data[0])
+	}
+	data = data[1:]
+	
+	h := &Hasher{}
+	for i := range h.state {
+		var b [32]byte
+		copy(b[:], data[:32])
+		h.state[i] = FromBytes(b)
+		if h.state[i].ToBytes32() != b {
+			return fmt.Errorf("transcript state element %d is not canonical", i)
+		}
+		data = data[32:]
+	}
+	absorbed := binary.BigEndian.Uint64(data[0:8])
+	squeezed := binary.BigEndian.Uint64(data[8:16])
+	permuted, absorbRate, squeezeRate := data[16], int(data[17]), int(data[18])
+	
+	if absorbRate < 1 || absorbRate >= T || squeezeRate < 1 || squeezeRate >= T {
+		return fmt.Errorf("transcript rates %d/%d out of range [1, %d]", absorbRate, squeezeRate, T-1)
+	}
+	if absorbed >= uint64(absorbRate) || squeezed > uint64(squeezeRate) {
+		return errors.New("transcript block counters are inconsistent with its rates")
+	}
+	if permuted > 1 {
+		return fmt.Errorf("invalid transcript permuted flag %d", permuted)
+	}
+	
+	h.absorbed, h.squeezed = int(absorbed), int(squeezed)
+	h.permuted = permuted == 1
+	h.absorbRate, h.squeezeRate = absorbRate, squeezeRate
+	t.h = h
+	return nil
+}
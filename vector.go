@@ -0,0 +1,115 @@
+package poseidon2
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// MulAdd sets acc = acc + x*y
+func MulAdd(acc *Fr, x, y *Fr) {
+	var product Fr
+	product.Mul(x, y)
+	acc.Add(acc, &product)
+}
+
+// DotProduct returns the sum of a[i]*b[i]
+// The slices must have equal length; two empty slices give zero
+func DotProduct(a, b []Fr) (Fr, error) {
+	if len(a) != len(b) {
+		return Fr{}, fmt.Errorf("dot product of vectors with different lengths (%d != %d)", len(a), len(b))
+	}
+	
+	var sum Fr
+	for i := range a {
+		MulAdd(&sum, &a[i], &b[i])
+	}
+	return sum, nil
+}
+
+// SumFr returns the field sum of elements with a single reduction at the end
+// Canonical elements are below 2^254, so they are added as plain 256-bit integers
+// into a five-limb accumulator whose top limb counts carries; that cannot overflow
+// for fewer than 2^64 elements. The 320-bit total L + H*2^256 is then reduced using
+// 2^256 = R (mod r): L with one Montgomery Mul by R, H*R with one Mul by R^2
+func SumFr(elements []Fr) Fr {
+	var acc Fr
+	var hi, carry uint64
+	for i := range elements {
+		e := &elements[i]
+		acc[0], carry = bits.Add64(acc[0], e[0], 0)
+		acc[1], carry = bits.Add64(acc[1], e[1], carry)
+		acc[2], carry = bits.Add64(acc[2], e[2], carry)
+		acc[3], carry = bits.Add64(acc[3], e[3], carry)
+		hi += carry
+	}
+	
+	// acc * R * R^-1 = acc (mod r); CIOS accepts a full 256-bit first operand
+	var low Fr
+	low.MulCIOS(&acc, &montgomeryR)
+	
+	// hi * R^2 * R^-1 = hi * 2^256 (mod r)
+	high := Fr{hi, 0, 0, 0}
+	high.MulCIOS(&high, &montgomeryR2)
+	
+	return *low.Add(&low, &high)
+}
+
+// BatchInverse returns the inverse of every element using one field inversion
+// Montgomery's trick replaces n inversions by one inversion and about 3n Mul.
+// Zero elements are skipped and their inverse is zero, matching Inverse
+func BatchInverse(elements []Fr) []Fr {
+	inverses, _ := BatchInverseWithMask(elements)
+	return inverses
+}
+
+// BatchInverseWithMask is BatchInverse that also reports which inputs were zero
+// zero[i] is true exactly when elements[i] is zero, in which case inverses[i] is zero;
+// callers building matrices from 1/(x+y) style formulas can use it to detect degenerate inputs
+func BatchInverseWithMask(elements []Fr) ([]Fr, []bool) {
+	inverses := make([]Fr, len(elements))
+	zero := make([]bool, len(elements))
+	
+	// inverses[i] temporarily holds the product of the non-zero elements before i
+	acc := One()
+	for i := range elements {
+		if elements[i].IsZero() {
+			zero[i] = true
+			continue
+		}
+		inverses[i] = acc
+		acc.Mul(&acc, &elements[i])
+	}
+	
+	// acc is now the product of all non-zero elements; walk back dividing it out
+	acc.Inverse(&acc)
+	for i := len(elements) - 1; i >= 0; i-- {
+		if zero[i] {
+			continue
+		}
+		inverses[i].Mul(&inverses[i], &acc)
+		acc.Mul(&acc, &elements[i])
+	}
+	return inverses, zero
+}
+
+// maxPowersOfTwo bounds PowersOfTwo to the bit length of a 32-byte value
+const maxPowersOfTwo = 256
+
+// PowersOfTwo returns [1, 2, 4, ..., 2^(n-1)] in Montgomery form by repeated Double
+// Recomposing a bit decomposition b is then DotProduct(b, PowersOfTwo(len(b)));
+// n must lie in [0, 256]. Powers from 2^254 on are reduced mod r
+func PowersOfTwo(n int) ([]Fr, error) {
+	if n < 0 || n > maxPowersOfTwo {
+		return nil, fmt.Errorf("power count %d out of range [0, %d]", n, maxPowersOfTwo)
+	}
+	
+	out := make([]Fr, n)
+	if n == 0 {
+		return out, nil
+	}
+	out[0] = One()
+	for i := 1; i < n; i++ {
+		out[i].Double(&out[i-1])
+	}
+	return out, nil
+}